@@ -0,0 +1,497 @@
+package parcours
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Sourcer is implemented by stores that know the source each line was
+// loaded from.
+type Sourcer interface {
+	// Source path of the line with id, as it was loaded
+	Source(ctx context.Context, id string) (path string, err error)
+}
+
+// LineKey identifies a line however its source is loaded, by the source
+// and a hash of the line's content.
+type LineKey struct {
+	Source string
+	Hash   string
+}
+
+// Bookmark of a line, by a hash of its content, with the id it was last
+// seen with to find it again, checked before it's relied on.
+type Bookmark struct {
+	Hash string `yaml:"hash"`
+	Note string `yaml:"note,omitempty"`
+	ID   string `yaml:"id,omitempty"`
+}
+
+// Sidecar of bookmarks of a source's lines, kept in a file next to it.
+type Sidecar struct {
+	Source string
+	Path   string
+	// Marks in order of hash
+	Marks []Bookmark
+}
+
+// LoadSidecar of source, with no bookmarks when its file is missing.
+func LoadSidecar(source string) (sc *Sidecar, err error) {
+
+	sc = &Sidecar{Source: source, Path: source + ".bookmarks"}
+	data, err := os.ReadFile(sc.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "failed to read bookmarks")
+		return
+	}
+
+	err = yaml.Unmarshal(data, &sc.Marks)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse bookmarks from %s", sc.Path)
+		return
+	}
+	slices.SortFunc(sc.Marks, func(a, b Bookmark) int { return cmp.Compare(a.Hash, b.Hash) })
+	return
+}
+
+// Save bookmarks to the sidecar file, replacing it whole.
+func (sc *Sidecar) Save() (err error) {
+
+	data, err := yaml.Marshal(sc.Marks)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to encode bookmarks")
+		return
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(sc.Path), filepath.Base(sc.Path)+".*")
+	if err != nil {
+		err = errors.Wrapf(err, "failed to create bookmarks file")
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		tmp.Close()
+		err = errors.Wrapf(err, "failed to write bookmarks")
+		return
+	}
+
+	err = os.Rename(tmp.Name(), sc.Path)
+	err = errors.Wrapf(err, "failed to replace bookmarks")
+	return
+}
+
+// Get the bookmark of the line with hash.
+func (sc *Sidecar) Get(hash string) (bm Bookmark, ok bool) {
+
+	idx, found := sc.find(hash)
+	if !found {
+		return
+	}
+	return sc.Marks[idx], true
+}
+
+// Set a bookmark, replacing any of the line with its hash.
+func (sc *Sidecar) Set(bm Bookmark) {
+
+	idx, found := sc.find(bm.Hash)
+	if found {
+		sc.Marks[idx] = bm
+		return
+	}
+	sc.Marks = slices.Insert(sc.Marks, idx, bm)
+}
+
+// Remove the bookmark of the line with hash, if any.
+func (sc *Sidecar) Remove(hash string) {
+
+	if idx, found := sc.find(hash); found {
+		sc.Marks = slices.Delete(sc.Marks, idx, idx+1)
+	}
+}
+
+// Bookmarks of lines from the sources loaded, in a sidecar for each.
+type Bookmarks struct {
+	Sidecars []*Sidecar
+
+	// keys of lines by id, as worked out
+	keys map[string]LineKey
+}
+
+// LoadBookmarks from the sidecars of sources.
+func LoadBookmarks(sources []string) (bms *Bookmarks, err error) {
+
+	bms = &Bookmarks{keys: map[string]LineKey{}}
+	for _, source := range sources {
+		_, err = bms.sidecar(source)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Get the bookmark of the line with id, once its key is known.
+func (bms *Bookmarks) Get(id string) (bm Bookmark, ok bool) {
+
+	if bms == nil {
+		return
+	}
+	key, known := bms.keys[id]
+	if !known {
+		return
+	}
+	return bms.lookup(key)
+}
+
+// Set the bookmark of the line with key, seen with id, saving its sidecar.
+func (bms *Bookmarks) Set(key LineKey, id, note string) (err error) {
+
+	sc, err := bms.sidecar(key.Source)
+	if err != nil {
+		return
+	}
+	bms.keys[id] = key
+	sc.Set(Bookmark{Hash: key.Hash, Note: note, ID: id})
+	return sc.Save()
+}
+
+// Remove the bookmark of the line with key, saving its sidecar.
+func (bms *Bookmarks) Remove(key LineKey) (err error) {
+
+	sc, err := bms.sidecar(key.Source)
+	if err != nil {
+		return
+	}
+	sc.Remove(key.Hash)
+	return sc.Save()
+}
+
+// Learn the keys of lines by id, bookmarks taking the ids their lines are
+// seen with, saving sidecars of those that change.
+func (bms *Bookmarks) Learn(keys map[string]LineKey) (err error) {
+
+	changed := map[*Sidecar]bool{}
+	for id, key := range keys {
+		bms.keys[id] = key
+		for _, sc := range bms.Sidecars {
+			idx, found := sc.find(key.Hash)
+			if sc.Source == key.Source && found && sc.Marks[idx].ID != id {
+				sc.Marks[idx].ID = id
+				changed[sc] = true
+			}
+		}
+	}
+
+	for _, sc := range bms.Sidecars {
+		if changed[sc] && err == nil {
+			err = sc.Save()
+		}
+	}
+	return
+}
+
+// ContentHash of a line's record, as its json with keys in order.
+func ContentHash(data map[string]any) (hash string, err error) {
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to encode record for hashing")
+		return
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// RenderNote renders the note of a bookmark, if any.
+func RenderNote(bm Bookmark, width int) string {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Width(width).Render("● " + bm.Note)
+}
+
+// unexported
+
+type bookmarkMsg struct {
+	key  LineKey
+	id   string
+	note string
+	// toggle the bookmark off when the line has one, rather than set it
+	toggle bool
+	err    error
+}
+
+type bookmarksCheckedMsg struct {
+	// keys of lines worked out, by id
+	keys map[string]LineKey
+	// jump to the line with id, if any
+	jump string
+	err  error
+}
+
+// find the bookmark of the line with hash, or where it would go
+func (sc *Sidecar) find(hash string) (idx int, found bool) {
+	return slices.BinarySearchFunc(sc.Marks, hash, func(bm Bookmark, hash string) int { return cmp.Compare(bm.Hash, hash) })
+}
+
+// sidecar of source, loaded the first time it's asked for
+func (bms *Bookmarks) sidecar(source string) (sc *Sidecar, err error) {
+
+	idx := slices.IndexFunc(bms.Sidecars, func(sc *Sidecar) bool { return sc.Source == source })
+	if idx >= 0 {
+		return bms.Sidecars[idx], nil
+	}
+
+	sc, err = LoadSidecar(source)
+	if err != nil {
+		return
+	}
+	bms.Sidecars = append(bms.Sidecars, sc)
+	return
+}
+
+// lookup the bookmark of the line with key
+func (bms *Bookmarks) lookup(key LineKey) (bm Bookmark, ok bool) {
+
+	for _, sc := range bms.Sidecars {
+		if sc.Source == key.Source {
+			return sc.Get(key.Hash)
+		}
+	}
+	return
+}
+
+// marked keys, for commands to check lines against
+func (bms *Bookmarks) marked() (marked map[LineKey]bool) {
+
+	marked = map[LineKey]bool{}
+	for _, sc := range bms.Sidecars {
+		for _, bm := range sc.Marks {
+			marked[LineKey{Source: sc.Source, Hash: bm.Hash}] = true
+		}
+	}
+	return
+}
+
+// only source of lines, when there's just the one, or else empty
+func (bms *Bookmarks) only() string {
+
+	if len(bms.Sidecars) != 1 {
+		return ""
+	}
+	return bms.Sidecars[0].Source
+}
+
+// compareIDs numerically, as ids count lines loaded, or else as text
+func compareIDs(a, b string) int {
+
+	numA, errA := strconv.ParseInt(a, 10, 64)
+	numB, errB := strconv.ParseInt(b, 10, 64)
+	if errA != nil || errB != nil {
+		return cmp.Compare(a, b)
+	}
+	return cmp.Compare(numA, numB)
+}
+
+// lineKey of the line with id, from its content and source, the source
+// being only when the store doesn't know it
+func lineKey(ctx context.Context, store Store, only, id string) (key LineKey, err error) {
+
+	data, err := store.GetJson(ctx, id)
+	if err != nil {
+		return
+	}
+	key.Hash, err = ContentHash(data)
+	if err != nil {
+		return
+	}
+
+	if sourcer, ok := store.(Sourcer); ok {
+		key.Source, err = sourcer.Source(ctx, id)
+		return
+	}
+	if only == "" {
+		err = errors.Errorf("store does not know the sources of lines")
+		return
+	}
+	key.Source = only
+	return
+}
+
+// lineKeys of lines with ids, passing over those that are gone
+func lineKeys(ctx context.Context, store Store, only string, ids []string) (keys map[string]LineKey, err error) {
+
+	keys = map[string]LineKey{}
+	for _, id := range ids {
+		key, keyErr := lineKey(ctx, store, only, id)
+		err = ctx.Err()
+		if err != nil {
+			return
+		}
+		if keyErr == nil {
+			keys[id] = key
+		}
+	}
+	return
+}
+
+// checkBookmarks works out the keys of lines buffered, when any are
+// bookmarked, to mark those that are
+func (m Model) checkBookmarks() tea.Cmd {
+	if m.Bookmarks == nil || len(m.Bookmarks.marked()) == 0 {
+		return nil
+	}
+
+	var ids []string
+	for _, line := range m.buffer {
+		id := line.ID(m.Fields)
+		if _, known := m.Bookmarks.keys[id]; !known && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	only := m.Bookmarks.only()
+	return func() tea.Msg {
+		keys, err := lineKeys(m.loads.ctx, m.Store, only, ids)
+		return bookmarksCheckedMsg{keys: keys, err: err}
+	}
+}
+
+// bookmark the selected line with note, or toggle its bookmark
+func (m Model) bookmark(note string, toggle bool) tea.Cmd {
+	id := m.selectedID()
+	if m.Bookmarks == nil || id == "" {
+		return nil
+	}
+	only := m.Bookmarks.only()
+	return func() tea.Msg {
+		key, err := lineKey(m.loads.ctx, m.Store, only, id)
+		return bookmarkMsg{key: key, id: id, note: note, toggle: toggle, err: err}
+	}
+}
+
+// setBookmark as asked, saving bookmarks
+func (m Model) setBookmark(msg bookmarkMsg) (Model, tea.Cmd) {
+	if msg.err != nil {
+		m.Err = msg.err
+		return m, nil
+	}
+
+	if _, ok := m.Bookmarks.lookup(msg.key); ok && msg.toggle {
+		m.Bookmarks.keys[msg.id] = msg.key
+		m.Err = m.Bookmarks.Remove(msg.key)
+	} else {
+		m.Err = m.Bookmarks.Set(msg.key, msg.id, msg.note)
+	}
+	return m, nil
+}
+
+// openNote opens a prompt for a note on the selected line's bookmark
+func (m Model) openNote() (Model, tea.Cmd) {
+	if m.Bookmarks == nil || m.selectedID() == "" {
+		return m, nil
+	}
+	bm, _ := m.Bookmarks.Get(m.selectedID())
+	m.Prompt, m.prompting = NewPrompt("note> ", bm.Note), notePrompt
+	return m, nil
+}
+
+// nextBookmark jumps to the next bookmarked line after the selected one by
+// id, wrapping around, among lines seen bookmarked and those bookmarks were
+// last seen with, the latter being checked first
+func (m Model) nextBookmark() tea.Cmd {
+	if m.Bookmarks == nil {
+		return nil
+	}
+
+	marked := m.Bookmarks.marked()
+	candidates := map[string]LineKey{}
+	for id, key := range m.Bookmarks.keys {
+		if marked[key] {
+			candidates[id] = key
+		}
+	}
+	var ids []string
+	for _, sc := range m.Bookmarks.Sidecars {
+		for _, bm := range sc.Marks {
+			if _, known := m.Bookmarks.keys[bm.ID]; !known && bm.ID != "" {
+				ids = append(ids, bm.ID)
+			}
+		}
+	}
+	for id := range candidates {
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// those after the selected line first
+	slices.SortFunc(ids, compareIDs)
+	ids = slices.Compact(ids)
+	idx, found := slices.BinarySearchFunc(ids, m.selectedID(), compareIDs)
+	if found {
+		idx++
+	}
+	ids = append(ids[idx:], ids[:idx]...)
+
+	only := m.Bookmarks.only()
+	return func() tea.Msg {
+		keys := map[string]LineKey{}
+		for _, id := range ids {
+			key, known := candidates[id]
+			if !known {
+				var err error
+				key, err = lineKey(m.loads.ctx, m.Store, only, id)
+				if ctxErr := m.loads.ctx.Err(); ctxErr != nil {
+					return bookmarksCheckedMsg{keys: keys, err: ctxErr}
+				}
+				if err != nil {
+					continue
+				}
+				keys[id] = key
+			}
+			if marked[key] {
+				return bookmarksCheckedMsg{keys: keys, jump: id}
+			}
+		}
+		return bookmarksCheckedMsg{keys: keys}
+	}
+}
+
+// checkedBookmarks learns the keys of lines worked out, jumping as asked
+func (m Model) checkedBookmarks(msg bookmarksCheckedMsg) (Model, tea.Cmd) {
+	err := m.Bookmarks.Learn(msg.keys)
+	if msg.err == nil {
+		msg.err = err
+	}
+	if msg.err != nil {
+		m.Err = msg.err
+		return m, nil
+	}
+	if msg.jump != "" {
+		return m, m.jump(msg.jump)
+	}
+	return m, nil
+}
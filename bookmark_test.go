@@ -0,0 +1,188 @@
+package parcours
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSidecar(t *testing.T) {
+
+	source := filepath.Join(t.TempDir(), "app.log")
+	sc, err := LoadSidecar(source)
+	if err != nil {
+		t.Fatalf("failed to load missing sidecar: %+v", err)
+	}
+	if sc.Path != source+".bookmarks" || len(sc.Marks) != 0 {
+		t.Fatalf("expected empty sidecar beside source, got %+v", sc)
+	}
+
+	sc.Set(Bookmark{Hash: "cc", ID: "3"})
+	sc.Set(Bookmark{Hash: "aa", Note: "first", ID: "1"})
+	sc.Set(Bookmark{Hash: "bb", ID: "2"})
+	sc.Set(Bookmark{Hash: "cc", Note: "replaced", ID: "7"})
+	sc.Remove("bb")
+	sc.Remove("zz")
+
+	expected := []Bookmark{{Hash: "aa", Note: "first", ID: "1"}, {Hash: "cc", Note: "replaced", ID: "7"}}
+	if !reflect.DeepEqual(sc.Marks, expected) {
+		t.Errorf("expected marks %+v, got %+v", expected, sc.Marks)
+	}
+	if bm, ok := sc.Get("cc"); !ok || bm.Note != "replaced" {
+		t.Errorf("expected to get replaced bookmark, got %+v %v", bm, ok)
+	}
+	if _, ok := sc.Get("bb"); ok {
+		t.Errorf("expected removed bookmark to be gone")
+	}
+
+	err = sc.Save()
+	if err != nil {
+		t.Fatalf("failed to save: %+v", err)
+	}
+	loaded, err := LoadSidecar(source)
+	if err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+	if !reflect.DeepEqual(loaded, sc) {
+		t.Errorf("expected %+v after round trip, got %+v", sc, loaded)
+	}
+
+	// malformed sidecars fail to load
+	err = os.WriteFile(sc.Path, []byte("{not: [yaml"), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write: %+v", err)
+	}
+	_, err = LoadSidecar(source)
+	if err == nil {
+		t.Errorf("expected error loading a malformed sidecar")
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+
+	bms, err := LoadBookmarks([]string{first, second})
+	if err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+	if len(bms.Sidecars) != 2 {
+		t.Fatalf("expected a sidecar per source, got %d", len(bms.Sidecars))
+	}
+
+	// the same content in both sources, bookmarked in one
+	inFirst, inSecond := LineKey{Source: first, Hash: "aa"}, LineKey{Source: second, Hash: "aa"}
+	err = bms.Set(inFirst, "1", "note")
+	if err != nil {
+		t.Fatalf("failed to set: %+v", err)
+	}
+
+	err = bms.Learn(map[string]LineKey{"1": inFirst, "2": inSecond})
+	if err != nil {
+		t.Fatalf("failed to learn: %+v", err)
+	}
+	if bm, ok := bms.Get("1"); !ok || bm.Note != "note" {
+		t.Errorf("expected bookmark of line 1, got %+v %v", bm, ok)
+	}
+	if _, ok := bms.Get("2"); ok {
+		t.Errorf("expected line 2, from the other source, to be unmarked")
+	}
+	if _, ok := bms.Get("3"); ok {
+		t.Errorf("expected line 3, with no key known, to be unmarked")
+	}
+
+	// reloaded with ids shifted, so the bookmark's found on another line
+	bms, err = LoadBookmarks([]string{second, first})
+	if err != nil {
+		t.Fatalf("failed to reload: %+v", err)
+	}
+	if _, ok := bms.Get("1"); ok {
+		t.Errorf("expected nothing marked before keys are known")
+	}
+	err = bms.Learn(map[string]LineKey{"1": inSecond, "9": inFirst})
+	if err != nil {
+		t.Fatalf("failed to learn: %+v", err)
+	}
+	if _, ok := bms.Get("1"); ok {
+		t.Errorf("expected line 1, now from the other source, to be unmarked")
+	}
+	if bm, ok := bms.Get("9"); !ok || bm.Note != "note" {
+		t.Errorf("expected bookmark to follow its line to 9, got %+v %v", bm, ok)
+	}
+
+	sc, err := LoadSidecar(first)
+	if err != nil {
+		t.Fatalf("failed to load sidecar: %+v", err)
+	}
+	if len(sc.Marks) != 1 || sc.Marks[0].ID != "9" {
+		t.Errorf("expected the id the line was seen with saved, got %+v", sc.Marks)
+	}
+	if _, err := os.Stat(second + ".bookmarks"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no sidecar written for a source without bookmarks")
+	}
+
+	err = bms.Remove(inFirst)
+	if err != nil {
+		t.Fatalf("failed to remove: %+v", err)
+	}
+	if _, ok := bms.Get("9"); ok {
+		t.Errorf("expected removed bookmark to be gone")
+	}
+}
+
+func TestLineKey(t *testing.T) {
+
+	data := map[string]any{"msg": "hello", "n": 1}
+	hash, err := ContentHash(data)
+	if err != nil {
+		t.Fatalf("failed to hash: %+v", err)
+	}
+
+	// source taken from a store that knows it, or else from the only one
+	key, err := lineKey(t.Context(), sourcedStore{data: data}, "", "1")
+	if err != nil {
+		t.Fatalf("failed to key line: %+v", err)
+	}
+	if key != (LineKey{Source: "from.log", Hash: hash}) {
+		t.Errorf("expected key from store's source, got %+v", key)
+	}
+
+	key, err = lineKey(t.Context(), jsonStore{data: data}, "only.log", "1")
+	if err != nil {
+		t.Fatalf("failed to key line: %+v", err)
+	}
+	if key != (LineKey{Source: "only.log", Hash: hash}) {
+		t.Errorf("expected key from only source, got %+v", key)
+	}
+
+	_, err = lineKey(t.Context(), jsonStore{data: data}, "", "1")
+	if err == nil {
+		t.Errorf("expected error keying a line of unknown source")
+	}
+}
+
+// jsonStore has lines of data, and nothing else
+type jsonStore struct {
+	Store
+	data map[string]any
+}
+
+func (st jsonStore) GetJson(ctx context.Context, id string) (map[string]any, error) {
+	return st.data, nil
+}
+
+// sourcedStore has lines of data from from.log
+type sourcedStore jsonStore
+
+func (st sourcedStore) GetJson(ctx context.Context, id string) (map[string]any, error) {
+	return st.data, nil
+}
+
+func (st sourcedStore) Source(ctx context.Context, id string) (string, error) {
+	return "from.log", nil
+}
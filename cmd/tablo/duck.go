@@ -0,0 +1,27 @@
+//go:build !noduck
+
+package main
+
+import (
+	"flag"
+
+	"parcours"
+	"parcours/store/duck"
+)
+
+var (
+	retainRows = flag.Int("retain-rows", 0, "rows kept by the duck store while following, all if zero")
+	retainAge  = flag.Duration("retain-age", 0, "age of rows kept by the duck store while following, relative to the newest, all if zero")
+)
+
+func init() {
+	stores["duck"] = func(dec *parcours.Decoder, lgr parcours.Logger) (parcours.Store, error) {
+		dk, err := duck.New(dec, lgr)
+		if err != nil {
+			return nil, err
+		}
+
+		dk.Retain(duck.Retention{Rows: *retainRows, Age: *retainAge})
+		return dk, nil
+	}
+}
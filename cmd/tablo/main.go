@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
-
-	_ "github.com/marcboeker/go-duckdb"
+	"strings"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
+	"github.com/pkg/errors"
 	"parcours"
-	"parcours/store/duck"
+	"parcours/store/remote"
 )
 
 // Simple logger that prints to stdout
@@ -23,29 +26,215 @@ func (l *simpleLogger) Error(ctx context.Context, msg string, err error, kv ...a
 	fmt.Printf("[ERROR] %s: %v %v\n", msg, err, kv)
 }
 
+// Usage:
+//
+//	tablo [flags] [log file...]        browse log files
+//	tablo [flags] serve [log file...]  serve a store over http
+//	tablo -remote url [log file...]    browse a served store, loading from its host
 func main() {
 
-	logger := &simpleLogger{}
-	dk, err := duck.New(logger)
+	layoutPath := flag.String("layout", "layout.yaml", "layout config file")
+	parser := flag.String("parser", "", "named parser from layout for plain-text logs, ndjson if empty")
+	follow := flag.Bool("follow", false, "follow the log file for appended lines")
+	storeName := flag.String("store", defaultStore(), "store, one of: "+strings.Join(storeNames(), ", "))
+	addr := flag.String("addr", "127.0.0.1:7070", "listen address when serving")
+	serveQuery := flag.Bool("serve-query", false, "let remote clients run read-only SQL when serving")
+	remoteURL := flag.String("remote", "", "url of a served store to browse")
+	since := flag.String("since", "", "load records from, a timestamp or relative to now like -2h")
+	until := flag.String("until", "", "load records up to, a timestamp or relative to now like -1h")
+	viewsPath := flag.String("views", "views.yaml", "saved views file")
+	viewName := flag.String("view", "", "saved view to start in")
+	filterExpr := flag.String("filter", "", `filter expression, such as 'level>=warn and message~"refresh.*"', within any view`)
+	flag.Parse()
+
+	args := flag.Args()
+	serving := len(args) > 0 && args[0] == "serve"
+	if serving {
+		args = args[1:]
+	}
+
+	//logFiles := []string{"test/data/smar.log"}
+	logFiles := []string{"junk/tag2.log"}
+	switch {
+	case len(args) > 0:
+		logFiles = args
+	case serving, *remoteURL != "":
+		logFiles = nil
+	}
+
+	layout, err := parcours.LoadLayout(*layoutPath)
 	if err != nil {
 		panic(err)
 	}
-	defer dk.Close()
 
-	//logFile := "test/data/smar.log"
-	logFile := "junk/tag2.log"
-	if err := dk.Load(logFile, 0); err != nil {
-		panic(err)
+	opts, err := loadOptions(*since, *until)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger := &simpleLogger{}
+	var store parcours.Store
+	if *remoteURL != "" {
+		store = remote.NewClient(*remoteURL, nil)
+	} else {
+		store, err = newLocal(layout, *parser, *storeName, logger)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
+	if closer, ok := store.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-	if err := dk.SetView(parcours.Filter{}, nil); err != nil {
+	if serving {
+		err = loadFiles(ctx, store, logFiles, *follow, opts)
+		if err != nil {
+			panic(err)
+		}
+
+		logger.Info(ctx, "serving store", "addr", *addr)
+		// clients may only load the files named here
+		svrOpts := remote.ServerOptions{Paths: logFiles, Query: *serveQuery}
+		err = http.ListenAndServe(*addr, remote.NewServer(ctx, store, logger, svrOpts))
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	views, err := parcours.LoadViews(*viewsPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	var view parcours.SavedView
+	if *viewName != "" {
+		var ok bool
+		view, ok = views.Get(*viewName)
+		if !ok {
+			fmt.Printf("Error: no view named %s in %s\n", *viewName, *viewsPath)
+			os.Exit(1)
+		}
+	}
+
+	if *filterExpr != "" {
+		filter, err := parcours.ParseFilter(*filterExpr)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			if syntaxErr, ok := err.(*parcours.SyntaxError); ok {
+				fmt.Printf("  %s\n  %*s\n", *filterExpr, syntaxErr.Column(), "^")
+			}
+			os.Exit(1)
+		}
+		if view.Filter.String() != "" {
+			filter = parcours.Filter{Op: parcours.And, Children: []*parcours.Filter{&view.Filter, &filter}}
+		}
+		view.Filter = filter
+	}
+
+	if err := store.SetView(ctx, view.Filter, view.Sorts); err != nil {
 		panic(err)
 	}
 
-	model := parcours.NewModel(dk)
+	// promotes layout fields, ahead of loading
+	model, err := parcours.NewModel(ctx, store, layout)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	model.Filter, model.Sorts, model.Columns = view.Filter, view.Sorts, view.Columns
+	model.Views = views
+	if *follow || *remoteURL != "" {
+		// the server may well be following
+		model.Tail, err = store.Tail(ctx)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if len(logFiles) > 0 && *remoteURL == "" {
+		// in a sidecar beside each file, so not for files on a server
+		model.Bookmarks, err = parcours.LoadBookmarks(logFiles)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 	p := tea.NewProgram(model)
+
+	// load in the background, pages being browsable as they arrive
+	loadErr := make(chan error, 1)
+	go func() {
+		err := loadFiles(ctx, store, logFiles, *follow, opts)
+		if err != nil {
+			loadErr <- err
+			p.Quit()
+		}
+	}()
+
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	select {
+	case err := <-loadErr:
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	default:
+	}
+}
+
+// loadFiles loads or follows log files in turn, their lines sharing the view
+func loadFiles(ctx context.Context, store parcours.Store, paths []string, follow bool, opts parcours.LoadOptions) (err error) {
+
+	for _, path := range paths {
+		if follow {
+			err = store.Follow(ctx, path, opts)
+		} else {
+			err = store.Load(ctx, path, opts)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// loadOptions from since and until flags
+func loadOptions(since, until string) (opts parcours.LoadOptions, err error) {
+
+	now := time.Now()
+	opts.Since, err = parcours.ParseBound(since, now)
+	if err != nil {
+		return
+	}
+	opts.Until, err = parcours.ParseBound(until, now)
+	if err != nil {
+		return
+	}
+
+	if !opts.Since.IsZero() && !opts.Until.IsZero() && opts.Until.Before(opts.Since) {
+		err = errors.Errorf("until %s is before since %s", until, since)
+	}
+	return
+}
+
+// newLocal creates a named store decoding with the layout's parser
+func newLocal(layout *parcours.Layout, parser, name string, lgr parcours.Logger) (store parcours.Store, err error) {
+
+	create, ok := stores[name]
+	if !ok {
+		err = errors.Errorf("unknown store %q", name)
+		return
+	}
+
+	decoder, err := layout.Decoder(parser)
+	if err != nil {
+		return
+	}
+
+	store, err = create(decoder, lgr)
+	return
 }
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"sort"
+
+	"parcours"
+	"parcours/store/memory"
+	"parcours/store/sqlite"
+)
+
+var sqlitePath = flag.String("db", "", "database file for the sqlite store, in memory if empty")
+
+type newStore func(dec *parcours.Decoder, lgr parcours.Logger) (parcours.Store, error)
+
+// stores by name, duck registering itself unless built with the noduck tag
+var stores = map[string]newStore{
+	"memory": func(dec *parcours.Decoder, lgr parcours.Logger) (parcours.Store, error) {
+		return memory.New(dec, lgr)
+	},
+	"sqlite": func(dec *parcours.Decoder, lgr parcours.Logger) (parcours.Store, error) {
+		return sqlite.New(*sqlitePath, dec, lgr)
+	},
+}
+
+func defaultStore() string {
+	if _, ok := stores["duck"]; ok {
+		return "duck"
+	}
+	return "memory"
+}
+
+func storeNames() (names []string) {
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
@@ -6,8 +6,6 @@ import (
 	"log"
 	"os"
 
-	_ "github.com/marcboeker/go-duckdb"
-
 	"parcours"
 	"parcours/store/duck"
 )
@@ -33,26 +31,27 @@ func main() {
 
 	// Create Duck store
 	logger := &simpleLogger{}
-	dk, err := duck.New(logger)
+	dk, err := duck.New(nil, logger)
 	if err != nil {
 		log.Fatalf("Failed to create Duck: %v", err)
 	}
 	defer dk.Close()
 
+	ctx := context.Background()
 	fmt.Printf("Loading logs from: %s\n\n", logFile)
 
 	// Load the log file
-	if err := dk.Load(logFile, 0); err != nil {
+	if err := dk.Load(ctx, logFile, parcours.LoadOptions{}); err != nil {
 		log.Fatalf("Failed to load logs: %v", err)
 	}
 
 	// Set empty view (no filter, no sort)
-	if err := dk.SetView(parcours.Filter{}, nil); err != nil {
+	if err := dk.SetView(ctx, parcours.Filter{}, nil); err != nil {
 		log.Fatalf("Failed to set view: %v", err)
 	}
 
 	// Get view info
-	fields, count, err := dk.GetView()
+	fields, count, err := dk.GetView(ctx)
 	if err != nil {
 		log.Fatalf("Failed to get view: %v", err)
 	}
@@ -68,7 +67,7 @@ func main() {
 
 	// Get first page of logs
 	pageSize := 5
-	lines, err := dk.GetPage(0, pageSize)
+	lines, err := dk.GetPage(ctx, 0, pageSize)
 	if err != nil {
 		log.Fatalf("Failed to get page: %v", err)
 	}
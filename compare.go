@@ -0,0 +1,306 @@
+package parcours
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+const (
+	// frequencyLimit is how many of a field's values are counted per side,
+	// those past it being counted one by one when on the other side
+	frequencyLimit = 1000
+	// minScore is how many standard errors apart rates must be to differ
+	minScore = 3
+)
+
+// Frequency of a value among lines.
+type Frequency struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Counter is implemented by stores that count the values of fields.
+type Counter interface {
+	// Frequencies of a field's values, as text, among lines matching filter,
+	// most frequent first and at most limit of them, with the lines matched
+	Frequencies(ctx context.Context, filter Filter, field string, limit int) (freqs []Frequency, total int, err error)
+}
+
+// Side of a comparison.
+type Side struct {
+	Label  string
+	Filter Filter
+}
+
+// Difference in how often a field has a value on either side.
+type Difference struct {
+	Field string
+	Value string
+	A, B  int
+	// Score is how many standard errors apart the rates are, B's being higher
+	// when positive
+	Score float64
+}
+
+// Comparison of two sides, with the values whose frequencies differ.
+type Comparison struct {
+	A, B           Side
+	TotalA, TotalB int
+	Differences    []Difference
+}
+
+// Compare how often fields have values on sides a and b, keeping those
+// that appear on only one side or whose rates differ significantly, most
+// different first.
+func Compare(ctx context.Context, counter Counter, a, b Side, fields []string) (comparison Comparison, err error) {
+
+	comparison.A, comparison.B = a, b
+	for _, field := range fields {
+		var freqsA, freqsB []Frequency
+		freqsA, comparison.TotalA, err = counter.Frequencies(ctx, a.Filter, field, frequencyLimit)
+		if err != nil {
+			return
+		}
+		freqsB, comparison.TotalB, err = counter.Frequencies(ctx, b.Filter, field, frequencyLimit)
+		if err != nil {
+			return
+		}
+
+		counts := map[string]*Difference{}
+		var values []string
+		count := func(freqs []Frequency, side func(*Difference) *int) {
+			for _, freq := range freqs {
+				diff, ok := counts[freq.Value]
+				if !ok {
+					diff = &Difference{Field: field, Value: freq.Value}
+					counts[freq.Value] = diff
+					values = append(values, freq.Value)
+				}
+				*side(diff) = freq.Count
+			}
+		}
+		count(freqsA, func(diff *Difference) *int { return &diff.A })
+		count(freqsB, func(diff *Difference) *int { return &diff.B })
+
+		for _, value := range values {
+			diff := counts[value]
+			if diff.A == 0 && len(freqsA) >= frequencyLimit {
+				diff.A, err = countValue(ctx, counter, a.Filter, field, value)
+			}
+			if diff.B == 0 && len(freqsB) >= frequencyLimit && err == nil {
+				diff.B, err = countValue(ctx, counter, b.Filter, field, value)
+			}
+			if err != nil {
+				return
+			}
+			diff.Score = score(diff.A, diff.B, comparison.TotalA, comparison.TotalB)
+			if diff.A == 0 || diff.B == 0 || math.Abs(diff.Score) >= minScore {
+				comparison.Differences = append(comparison.Differences, *diff)
+			}
+		}
+	}
+
+	slices.SortStableFunc(comparison.Differences, func(x, y Difference) int {
+		return cmp.Compare(math.Abs(y.Score), math.Abs(x.Score))
+	})
+	return
+}
+
+// Example filters for lines on the side where diff's value is more frequent.
+func (comparison Comparison) Example(diff Difference) (label string, filter Filter) {
+
+	side := comparison.A
+	if diff.Score > 0 {
+		side = comparison.B
+	}
+	match := Filter{Op: Eq, Field: diff.Field, Value: diff.Value}
+	return fmt.Sprintf("%s %s=%s", side.Label, diff.Field, diff.Value),
+		Filter{Op: And, Children: []*Filter{&side.Filter, &match}}
+}
+
+// CompareFields named by the layout, or else message, level and the
+// layout's visible columns.
+func (layout *Layout) CompareFields() []string {
+
+	if len(layout.Compare) > 0 {
+		return layout.Compare
+	}
+
+	fields := []string{"message", "level"}
+	for _, col := range layout.Columns {
+		if col.Hidden || col.Field == "timestamp" || slices.Contains(fields, col.Field) {
+			continue
+		}
+		fields = append(fields, col.Field)
+	}
+	return fields
+}
+
+// Differences of a comparison, shown in place of the view.
+type Differences struct {
+	Comparison Comparison
+	// Results lists the differences, a line for each
+	Results *Results
+}
+
+// NewDifferences lists the differences of comparison.
+func NewDifferences(comparison Comparison) *Differences {
+
+	fields := []Field{
+		{Name: "field", Type: "VARCHAR"},
+		{Name: "value", Type: "VARCHAR"},
+		{Name: comparison.A.Label, Type: "BIGINT"},
+		{Name: comparison.B.Label, Type: "BIGINT"},
+		{Name: "change", Type: "VARCHAR"},
+	}
+
+	lines := make([]Line, len(comparison.Differences))
+	for i, diff := range comparison.Differences {
+		lines[i] = Line{
+			{Raw: diff.Field},
+			{Raw: diff.Value},
+			{Raw: int64(diff.A)},
+			{Raw: int64(diff.B)},
+			{Raw: comparison.change(diff)},
+		}
+	}
+
+	return &Differences{
+		Comparison: comparison,
+		Results:    NewResults("", fields, lines),
+	}
+}
+
+// Selected difference, if any.
+func (diffs *Differences) Selected() (diff Difference, ok bool) {
+
+	at := diffs.Results.ScrollOffset + diffs.Results.SelectedRow
+	if at >= len(diffs.Comparison.Differences) {
+		return
+	}
+	return diffs.Comparison.Differences[at], true
+}
+
+// View renders the sides compared and a page of differences.
+func (diffs *Differences) View(width int, zone *time.Location) string {
+
+	comparison := diffs.Comparison
+	header := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).
+		Render(fmt.Sprintf("%s (%d lines) vs %s (%d lines) | %d differences | enter examples | esc close",
+			comparison.A.Label, comparison.TotalA, comparison.B.Label, comparison.TotalB, len(comparison.Differences)))
+	res := diffs.Results
+	return header + "\n" + RenderTable(res.Fields, res.Page(), res.SelectedRow, -1, width, res.Layout, zone, nil)
+}
+
+// unexported
+
+type compareMsg struct {
+	comparison Comparison
+	err        error
+}
+
+// score is the two-proportion z statistic of b/totalB over a/totalA
+func score(a, b, totalA, totalB int) float64 {
+
+	if totalA == 0 || totalB == 0 {
+		return 0
+	}
+	rateA, rateB := float64(a)/float64(totalA), float64(b)/float64(totalB)
+	pooled := float64(a+b) / float64(totalA+totalB)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/float64(totalA) + 1/float64(totalB)))
+	if se == 0 {
+		return 0
+	}
+	return (rateB - rateA) / se
+}
+
+// countValue is how many lines matching filter have field's value, for a
+// value past those counted
+func countValue(ctx context.Context, counter Counter, filter Filter, field, value string) (count int, err error) {
+
+	match := Filter{Op: Eq, Field: field, Value: value}
+	_, count, err = counter.Frequencies(ctx, Filter{Op: And, Children: []*Filter{&filter, &match}}, field, 1)
+	return
+}
+
+// change in diff's rate from side a to b, as text
+func (comparison Comparison) change(diff Difference) string {
+
+	switch {
+	case diff.A == 0:
+		return "only " + comparison.B.Label
+	case diff.B == 0:
+		return "only " + comparison.A.Label
+	}
+	rateA := float64(diff.A) / float64(comparison.TotalA)
+	rateB := float64(diff.B) / float64(comparison.TotalB)
+	return fmt.Sprintf("%+.0f%%", (rateB/rateA-1)*100)
+}
+
+// compare sides, listing their differences
+func (m Model) compare(a, b Side) tea.Cmd {
+	counter, ok := m.Store.(Counter)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		comparison, err := Compare(m.loads.ctx, counter, a, b, m.Layout.CompareFields())
+		return compareMsg{comparison: comparison, err: err}
+	}
+}
+
+// compareAround the selected line, lines in view before its time against
+// those from it on
+func (m Model) compareAround() tea.Cmd {
+	ts := m.selectedTime()
+	if ts.IsZero() {
+		return nil
+	}
+
+	label := ts.In(m.Zone).Format(time.TimeOnly)
+	split := func(op FilterOp) Filter {
+		filter := m.Filter
+		return Filter{Op: And, Children: []*Filter{&filter, {Op: op, Field: "timestamp", Value: ts}}}
+	}
+	return m.compare(Side{Label: "before " + label, Filter: split(Lt)}, Side{Label: "from " + label, Filter: split(Gte)})
+}
+
+// compareCrumbs compares the view pivoted from with the current one
+func (m Model) compareCrumbs() tea.Cmd {
+	if len(m.Crumbs) < 2 {
+		return nil
+	}
+	a, b := m.Crumbs[len(m.Crumbs)-2], m.Crumbs[len(m.Crumbs)-1]
+	return m.compare(Side{Label: a.Label, Filter: a.Filter}, Side{Label: b.Label, Filter: b.Filter})
+}
+
+// updateDifferences navigates differences until they're closed, pivoting
+// to examples of the selected one
+func (m Model) updateDifferences(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.Differences = nil
+	case "enter":
+		diff, ok := m.Differences.Selected()
+		if !ok {
+			return m, nil
+		}
+		label, filter := m.Differences.Comparison.Example(diff)
+		m.Differences = nil
+		return m.pivot(label, filter, []Sort{{Field: "timestamp"}})
+	case "z":
+		m.Zone = nextZone(m.Layout.Zones(), m.Zone)
+	default:
+		m.Differences.Results.Navigate(msg.String())
+	}
+	return m, nil
+}
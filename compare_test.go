@@ -0,0 +1,88 @@
+package parcours
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+
+	// a has many values of its own, more than are counted, crowding out one
+	// it shares with b at the same rate
+	var counter recordCounter
+	for i := range frequencyLimit {
+		for range 3 {
+			counter = append(counter, map[string]string{"side": "a", "code": fmt.Sprintf("c%04d", i)})
+		}
+	}
+	for range 2 {
+		counter = append(counter, map[string]string{"side": "a", "code": "shared"})
+	}
+	for range 2 {
+		counter = append(counter, map[string]string{"side": "b", "code": "shared"})
+	}
+	for range 3 {
+		counter = append(counter, map[string]string{"side": "b", "code": "new"})
+	}
+
+	sideA := Side{Label: "a", Filter: Filter{Op: Eq, Field: "side", Value: "a"}}
+	sideB := Side{Label: "b", Filter: Filter{Op: Eq, Field: "side", Value: "b"}}
+	comparison, err := Compare(t.Context(), counter, sideA, sideB, []string{"code"})
+	if err != nil {
+		t.Fatalf("failed to compare: %+v", err)
+	}
+	if comparison.TotalA != 3*frequencyLimit+2 || comparison.TotalB != 5 {
+		t.Errorf("expected totals %d and 5, got %d and %d", 3*frequencyLimit+2, comparison.TotalA, comparison.TotalB)
+	}
+
+	diffs := map[string]Difference{}
+	for _, diff := range comparison.Differences {
+		diffs[diff.Value] = diff
+	}
+	if diff, ok := diffs["shared"]; ok && (diff.A == 0 || diff.B == 0) {
+		t.Errorf("expected value past the limit counted on both sides, got %+v", diff)
+	}
+	if diff, ok := diffs["new"]; !ok || diff.A != 0 || diff.B != 3 {
+		t.Errorf("expected value only in b, got %+v %v", diff, ok)
+	}
+	if diff, ok := diffs["c0000"]; !ok || diff.A != 3 || diff.B != 0 {
+		t.Errorf("expected value only in a, got %+v %v", diff, ok)
+	}
+}
+
+// recordCounter counts values of records matching equality filters
+type recordCounter []map[string]string
+
+func (records recordCounter) Frequencies(ctx context.Context, filter Filter, field string, limit int) (freqs []Frequency, total int, err error) {
+
+	counts := map[string]int{}
+	for _, rec := range records {
+		if records.matches(rec, filter) {
+			counts[rec[field]]++
+			total++
+		}
+	}
+	for val, count := range counts {
+		freqs = append(freqs, Frequency{Value: val, Count: count})
+	}
+	slices.SortFunc(freqs, func(x, y Frequency) int {
+		return cmp.Or(cmp.Compare(y.Count, x.Count), cmp.Compare(x.Value, y.Value))
+	})
+	return freqs[:min(limit, len(freqs))], total, nil
+}
+
+func (records recordCounter) matches(rec map[string]string, filter Filter) bool {
+
+	if filter.Op == And {
+		for _, child := range filter.Children {
+			if !records.matches(rec, *child) {
+				return false
+			}
+		}
+		return true
+	}
+	return rec[filter.Field] == filter.Value
+}
@@ -0,0 +1,133 @@
+package parcours
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/pkg/errors"
+)
+
+// defaultCorrelate are the fields correlated on when a layout names none
+var defaultCorrelate = []string{"request_id", "trace_id", "run_id", "worker_id"}
+
+// Crumb is a view pivoted to, kept so that those before it can be restored.
+type Crumb struct {
+	Label   string
+	Filter  Filter
+	Sorts   []Sort
+	Columns []string
+}
+
+// Correlate builds a filter for lines sharing the value of any of fields
+// with the line with id, wherever they were loaded from, and a label for it.
+func Correlate(ctx context.Context, store Store, id string, fields []string) (filter Filter, label string, err error) {
+
+	data, err := store.GetJson(ctx, id)
+	if err != nil {
+		return
+	}
+
+	filter.Op = Or
+	var labels []string
+	for _, field := range fields {
+		val, ok := correlation(data[field])
+		if !ok {
+			continue
+		}
+		filter.Children = append(filter.Children, &Filter{Op: Eq, Field: field, Value: val})
+		labels = append(labels, field+"="+val)
+	}
+
+	if len(filter.Children) == 0 {
+		err = errors.Errorf("line %s has none of %s", id, strings.Join(fields, ", "))
+		return
+	}
+	label = strings.Join(labels, " or ")
+	return
+}
+
+// CorrelateFields named by the layout, or the defaults.
+func (layout *Layout) CorrelateFields() []string {
+
+	if len(layout.Correlate) == 0 {
+		return defaultCorrelate
+	}
+	return layout.Correlate
+}
+
+// RenderCrumbs renders the path of views pivoted through.
+func RenderCrumbs(crumbs []Crumb, width int) string {
+
+	labels := make([]string, len(crumbs))
+	for i, crumb := range crumbs {
+		labels[i] = crumb.Label
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Width(width).
+		Render(strings.Join(labels, " › ") + "  (esc back)")
+}
+
+// unexported
+
+type correlateMsg struct {
+	filter Filter
+	label  string
+	err    error
+}
+
+// correlation value of a raw field as text, as it's compared, if scalar.
+// Numbers are best decoded as json.Number, as ids past 2^53 don't survive
+// as floats.
+func correlation(val any) (text string, ok bool) {
+
+	switch val := val.(type) {
+	case string:
+		return val, val != ""
+	case json.Number:
+		return val.String(), true
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(val), true
+	}
+	return "", false
+}
+
+// correlate the selected line, pivoting to what shares its ids
+func (m Model) correlate() tea.Cmd {
+	id := m.selectedID()
+	if id == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		filter, label, err := Correlate(m.loads.ctx, m.Store, id, m.Layout.CorrelateFields())
+		return correlateMsg{filter: filter, label: label, err: err}
+	}
+}
+
+// pivot to a view, leaving a crumb to come back by
+func (m Model) pivot(label string, filter Filter, sorts []Sort) (Model, tea.Cmd) {
+	if len(m.Crumbs) == 0 {
+		m.Crumbs = []Crumb{{Label: "all", Filter: m.Filter, Sorts: m.Sorts, Columns: m.Columns}}
+	}
+	m.Crumbs = append(slices.Clip(m.Crumbs), Crumb{Label: label, Filter: filter, Sorts: sorts, Columns: m.Columns})
+	return m.setView(filter, sorts)
+}
+
+// back to the view before the last pivot
+func (m Model) back() (Model, tea.Cmd) {
+	if len(m.Crumbs) == 0 {
+		return m, nil
+	}
+	m.Crumbs = m.Crumbs[:len(m.Crumbs)-1]
+	crumb := m.Crumbs[len(m.Crumbs)-1]
+	if len(m.Crumbs) == 1 {
+		m.Crumbs = nil
+	}
+	m.Columns = crumb.Columns
+	return m.setView(crumb.Filter, crumb.Sorts)
+}
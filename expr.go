@@ -0,0 +1,473 @@
+package parcours
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// exprOps are the comparison operators of filter expressions, longest
+// first so that they're matched greedily
+var exprOps = []exprOp{
+	{"!=", Ne}, {">=", Gte}, {"<=", Lte}, {"*=", Contains},
+	{"=", Eq}, {">", Gt}, {"<", Lt}, {"~", Match},
+}
+
+// SyntaxError in a filter expression, at a byte offset into it.
+type SyntaxError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at column %d", err.Msg, err.Column())
+}
+
+// Column of the error, counting runes from 1.
+func (err *SyntaxError) Column() int {
+	return utf8.RuneCountInString(err.Expr[:err.Pos]) + 1
+}
+
+// ParseFilter parses a filter expression such as
+//
+//	level>=warn and app_id="tag" and message~"refresh.*" and worker_id!=null
+//
+// Comparisons are of a field with a value by =, !=, >, >=, <, <=, *= for
+// contains, or ~ for a regex match. Values are quoted text, numbers, true,
+// false, null or else bare text, such as timestamps, stores converting
+// them to suit the field. Comparisons combine with not, and and or, in
+// that order of precedence, and parentheses. An empty expression matches
+// everything.
+func ParseFilter(expr string) (filter Filter, err error) {
+
+	ps := &exprParser{expr: expr}
+	err = ps.lex()
+	if err != nil {
+		return
+	}
+	if ps.peek().kind == endToken {
+		return
+	}
+
+	filter, err = ps.or()
+	if err != nil {
+		return
+	}
+	if tok := ps.peek(); tok.kind != endToken {
+		err = ps.fail(tok.pos, "expected and, or or the end, got %s", tok)
+	}
+	return
+}
+
+// String renders the filter as an expression ParseFilter parses back to
+// it, empty for the zero filter.
+func (filter Filter) String() string {
+
+	switch filter.Op {
+	case And, Or:
+		if len(filter.Children) == 0 {
+			return ""
+		}
+		terms := make([]string, len(filter.Children))
+		for i, child := range filter.Children {
+			terms[i] = child.String()
+			// or binds looser than and, and nesting is kept as it is
+			if (child.Op == And || child.Op == Or) && len(child.Children) != 1 {
+				terms[i] = "(" + terms[i] + ")"
+			}
+		}
+		join := " and "
+		if filter.Op == Or {
+			join = " or "
+		}
+		return strings.Join(terms, join)
+
+	case Not:
+		if len(filter.Children) != 1 {
+			return "not ()"
+		}
+		child := filter.Children[0]
+		term := child.String()
+		if (child.Op == And || child.Op == Or) && len(child.Children) != 1 {
+			term = "(" + term + ")"
+		}
+		return "not " + term
+	}
+
+	op := ""
+	for _, eo := range exprOps {
+		if eo.op == filter.Op {
+			op = eo.text
+			break
+		}
+	}
+	return exprText(filter.Field, false) + op + exprValue(filter.Value)
+}
+
+// unexported
+
+// exprOp is a comparison operator as written
+type exprOp struct {
+	text string
+	op   FilterOp
+}
+
+type tokenKind int
+
+const (
+	endToken tokenKind = iota
+	wordToken
+	stringToken
+	opToken
+	openToken
+	closeToken
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	// text as written, unquoted for strings
+	text string
+	op   FilterOp
+}
+
+func (tok token) String() string {
+	switch tok.kind {
+	case endToken:
+		return "the end"
+	case stringToken:
+		return strconv.Quote(tok.text)
+	}
+	return fmt.Sprintf("%q", tok.text)
+}
+
+// exprParser parses by recursive descent over tokens lexed up front
+type exprParser struct {
+	expr   string
+	tokens []token
+	next   int
+}
+
+func (ps *exprParser) fail(pos int, format string, args ...any) error {
+	return &SyntaxError{Expr: ps.expr, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lex the expression into tokens, ending with an end token
+func (ps *exprParser) lex() (err error) {
+
+	expr := ps.expr
+	at := 0
+	for at < len(expr) {
+		r, size := utf8.DecodeRuneInString(expr[at:])
+		switch {
+		case unicode.IsSpace(r):
+			at += size
+			continue
+		case r == '(':
+			ps.tokens = append(ps.tokens, token{kind: openToken, pos: at, text: "("})
+			at++
+			continue
+		case r == ')':
+			ps.tokens = append(ps.tokens, token{kind: closeToken, pos: at, text: ")"})
+			at++
+			continue
+		case r == '"':
+			var tok token
+			tok, err = ps.lexString(at)
+			if err != nil {
+				return
+			}
+			ps.tokens = append(ps.tokens, tok)
+			at += ps.quotedLen(at)
+			continue
+		}
+
+		if op, ok := opAt(expr[at:]); ok {
+			ps.tokens = append(ps.tokens, token{kind: opToken, pos: at, text: op.text, op: op.op})
+			at += len(op.text)
+			continue
+		}
+
+		start := at
+		for at < len(expr) {
+			r, size := utf8.DecodeRuneInString(expr[at:])
+			if _, ok := opAt(expr[at:]); ok || unicode.IsSpace(r) || r == '(' || r == ')' || r == '"' {
+				break
+			}
+			at += size
+		}
+		ps.tokens = append(ps.tokens, token{kind: wordToken, pos: start, text: expr[start:at]})
+	}
+
+	ps.tokens = append(ps.tokens, token{kind: endToken, pos: len(expr)})
+	return
+}
+
+// lexString lexes a quoted string starting at start, with Go's escapes
+func (ps *exprParser) lexString(start int) (tok token, err error) {
+
+	end := start + ps.quotedLen(start)
+	if end > len(ps.expr) || end-start < 2 || ps.expr[end-1] != '"' {
+		err = ps.fail(start, "unterminated string")
+		return
+	}
+
+	text, err := strconv.Unquote(ps.expr[start:end])
+	if err != nil {
+		err = ps.fail(start, "invalid escape in string")
+		return
+	}
+	return token{kind: stringToken, pos: start, text: text}, nil
+}
+
+// quotedLen is the length of the quoted string at start, through its
+// closing quote or to the end of the expression when there's none
+func (ps *exprParser) quotedLen(start int) int {
+
+	at := start + 1
+	for at < len(ps.expr) {
+		switch ps.expr[at] {
+		case '\\':
+			at += 2
+			continue
+		case '"':
+			return at + 1 - start
+		}
+		at++
+	}
+	return len(ps.expr) + 1 - start
+}
+
+// opAt is the operator starting text, if any
+func opAt(text string) (op exprOp, ok bool) {
+
+	for _, op := range exprOps {
+		if strings.HasPrefix(text, op.text) {
+			return op, true
+		}
+	}
+	return
+}
+
+func (ps *exprParser) peek() token {
+	return ps.tokens[ps.next]
+}
+
+func (ps *exprParser) take() token {
+	tok := ps.tokens[ps.next]
+	if tok.kind != endToken {
+		ps.next++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the word kw, taking it if so
+func (ps *exprParser) keyword(kw string) bool {
+
+	tok := ps.peek()
+	if tok.kind == wordToken && strings.EqualFold(tok.text, kw) {
+		ps.next++
+		return true
+	}
+	return false
+}
+
+// or := and ("or" and)*
+func (ps *exprParser) or() (filter Filter, err error) {
+	return ps.join(Or, "or", ps.and)
+}
+
+// and := unary ("and" unary)*
+func (ps *exprParser) and() (filter Filter, err error) {
+	return ps.join(And, "and", ps.unary)
+}
+
+// join terms parsed by term with the keyword kw under op, a lone term
+// standing by itself
+func (ps *exprParser) join(op FilterOp, kw string, term func() (Filter, error)) (filter Filter, err error) {
+
+	filter, err = term()
+	if err != nil {
+		return
+	}
+	if !ps.keyword(kw) {
+		return
+	}
+
+	first := filter
+	filter = Filter{Op: op, Children: []*Filter{&first}}
+	for {
+		var next Filter
+		next, err = term()
+		if err != nil {
+			return
+		}
+		filter.Children = append(filter.Children, &next)
+		if !ps.keyword(kw) {
+			return
+		}
+	}
+}
+
+// unary := "not" unary | "(" or ")" | "()" | comparison
+func (ps *exprParser) unary() (filter Filter, err error) {
+
+	if ps.keyword("not") {
+		var child Filter
+		child, err = ps.unary()
+		filter = Filter{Op: Not, Children: []*Filter{&child}}
+		return
+	}
+
+	if ps.peek().kind != openToken {
+		return ps.comparison()
+	}
+	open := ps.take()
+	if ps.peek().kind == closeToken {
+		// an empty group matches everything
+		ps.take()
+		return
+	}
+
+	filter, err = ps.or()
+	if err != nil {
+		return
+	}
+	if tok := ps.take(); tok.kind != closeToken {
+		err = ps.fail(tok.pos, "expected ) to close ( at column %d, got %s", utf8.RuneCountInString(ps.expr[:open.pos])+1, tok)
+	}
+	return
+}
+
+// comparison := field op value
+func (ps *exprParser) comparison() (filter Filter, err error) {
+
+	field := ps.take()
+	if field.kind != wordToken && field.kind != stringToken {
+		err = ps.fail(field.pos, "expected a field, got %s", field)
+		return
+	}
+	if field.text == "" {
+		err = ps.fail(field.pos, "expected a field, got an empty name")
+		return
+	}
+
+	op := ps.take()
+	if op.kind != opToken {
+		err = ps.fail(op.pos, "expected an operator after %s, got %s", field.text, op)
+		return
+	}
+
+	val := ps.take()
+	filter = Filter{Op: op.op, Field: field.text}
+	switch val.kind {
+	case stringToken:
+		filter.Value = val.text
+	case wordToken:
+		if !strings.EqualFold(val.text, "and") && !strings.EqualFold(val.text, "or") {
+			filter.Value = exprWord(val.text)
+			break
+		}
+		// a value left out, more likely than one spelled so unquoted
+		fallthrough
+	default:
+		err = ps.fail(val.pos, "expected a value after %s%s, got %s", field.text, op.text, val)
+		return
+	}
+
+	if filter.Value == nil && op.op != Eq && op.op != Ne {
+		err = ps.fail(val.pos, "null can only be compared with = or !=")
+		return
+	}
+	if op.op == Match {
+		if _, reErr := regexp.Compile(fmt.Sprint(filter.Value)); reErr != nil {
+			err = ps.fail(val.pos, "invalid regex: %s", reErr)
+		}
+	}
+	return
+}
+
+// exprWord is the value of a bare word
+func exprWord(word string) any {
+
+	switch strings.ToLower(word) {
+	case "null":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if num, err := strconv.ParseInt(word, 10, 64); err == nil {
+		return num
+	}
+	// spelled as a number, rather than inf or nan
+	if strings.Trim(word, "0123456789.eE+-") == "" {
+		if num, err := strconv.ParseFloat(word, 64); err == nil {
+			return num
+		}
+	}
+	return word
+}
+
+// exprValue renders a value so that it parses back as the same
+func exprValue(val any) string {
+
+	switch val := val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case int:
+		return strconv.Itoa(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		text := strconv.FormatFloat(val, 'g', -1, 64)
+		if strings.Trim(text, "0123456789-") == "" {
+			// else it'd parse as an integer
+			text += ".0"
+		}
+		return exprText(text, true)
+	case Level:
+		return exprText(val.String(), false)
+	case time.Time:
+		return strconv.Quote(val.Format(time.RFC3339Nano))
+	case string:
+		return exprText(val, false)
+	}
+	return strconv.Quote(fmt.Sprint(val))
+}
+
+// exprText renders text bare where it would lex as a word with the same
+// value, quoted otherwise, numbers being bare only when they're meant to be
+func exprText(text string, number bool) string {
+
+	bare := text != "" && strings.IndexFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+	}) < 0
+	for at := range text {
+		if _, ok := opAt(text[at:]); ok {
+			bare = false
+		}
+	}
+
+	switch strings.ToLower(text) {
+	case "and", "or", "not", "null", "true", "false":
+		bare = false
+	}
+	if _, ok := exprWord(text).(string); !ok && !number {
+		bare = false
+	}
+
+	if bare {
+		return text
+	}
+	return strconv.Quote(text)
+}
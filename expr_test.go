@@ -0,0 +1,142 @@
+package parcours
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestParseFilter(t *testing.T) {
+
+	tests := []struct {
+		expr     string
+		expected Filter
+	}{
+		{expr: "", expected: Filter{}},
+		{expr: "()", expected: Filter{}},
+		{expr: "level>=warn", expected: Filter{Op: Gte, Field: "level", Value: "warn"}},
+		{expr: "n=12", expected: Filter{Op: Eq, Field: "n", Value: int64(12)}},
+		{expr: "n<-1.5", expected: Filter{Op: Lt, Field: "n", Value: -1.5}},
+		{expr: `n="12"`, expected: Filter{Op: Eq, Field: "n", Value: "12"}},
+		{expr: "ok!=TRUE", expected: Filter{Op: Ne, Field: "ok", Value: true}},
+		{expr: "worker_id=null", expected: Filter{Op: Eq, Field: "worker_id"}},
+		{expr: "ts>2025-11-13T20:30:00Z", expected: Filter{Op: Gt, Field: "ts", Value: "2025-11-13T20:30:00Z"}},
+		{expr: "ts<2025-11-13T20:30:00+01:00", expected: Filter{Op: Lt, Field: "ts", Value: "2025-11-13T20:30:00+01:00"}},
+		{expr: `"user name"*="a \"b\""`, expected: Filter{Op: Contains, Field: "user name", Value: `a "b"`}},
+		{expr: `msg~"^x.*"`, expected: Filter{Op: Match, Field: "msg", Value: "^x.*"}},
+		{expr: "not a=1 and b=2 or c=3", expected: Filter{Op: Or, Children: []*Filter{
+			{Op: And, Children: []*Filter{
+				{Op: Not, Children: []*Filter{{Op: Eq, Field: "a", Value: int64(1)}}},
+				{Op: Eq, Field: "b", Value: int64(2)},
+			}},
+			{Op: Eq, Field: "c", Value: int64(3)},
+		}}},
+		{expr: "a=1 AND (b=2 Or c=3)", expected: Filter{Op: And, Children: []*Filter{
+			{Op: Eq, Field: "a", Value: int64(1)},
+			{Op: Or, Children: []*Filter{
+				{Op: Eq, Field: "b", Value: int64(2)},
+				{Op: Eq, Field: "c", Value: int64(3)},
+			}},
+		}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			filter, err := ParseFilter(tc.expr)
+			if err != nil {
+				t.Fatalf("failed to parse: %+v", err)
+			}
+			if !reflect.DeepEqual(filter, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, filter)
+			}
+		})
+	}
+}
+
+func TestFilterRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		filter Filter
+		expr   string
+	}{
+		{filter: Filter{}, expr: ""},
+		{filter: Filter{Op: Eq, Field: "level", Value: "debug"}, expr: "level=debug"},
+		{filter: Filter{Op: Eq, Field: "worker_id"}, expr: "worker_id=null"},
+		{filter: Filter{Op: Eq, Field: "n", Value: int64(3)}, expr: "n=3"},
+		{filter: Filter{Op: Eq, Field: "n", Value: 3.0}, expr: "n=3.0"},
+		{filter: Filter{Op: Eq, Field: "n", Value: "3"}, expr: `n="3"`},
+		{filter: Filter{Op: Eq, Field: "s", Value: "null"}, expr: `s="null"`},
+		{filter: Filter{Op: Eq, Field: "s", Value: "or"}, expr: `s="or"`},
+		{filter: Filter{Op: Eq, Field: "s", Value: ""}, expr: `s=""`},
+		{filter: Filter{Op: Eq, Field: "s", Value: "a=b"}, expr: `s="a=b"`},
+		{filter: Filter{Op: Contains, Field: "user name", Value: `say "hi"`}, expr: `"user name"*="say \"hi\""`},
+		{filter: Filter{Op: Ne, Field: "ts", Value: "2025-11-13T20:30:00Z"}, expr: "ts!=2025-11-13T20:30:00Z"},
+		{filter: Filter{Op: Not, Children: []*Filter{
+			{Op: Or, Children: []*Filter{{Op: Eq, Field: "a", Value: "x"}, {Op: Eq, Field: "b", Value: "y"}}},
+		}}, expr: "not (a=x or b=y)"},
+		{filter: Filter{Op: And, Children: []*Filter{
+			{Op: Or, Children: []*Filter{{Op: Eq, Field: "a", Value: "x"}, {Op: Eq, Field: "b", Value: "y"}}},
+			{Op: Gt, Field: "c", Value: true},
+		}}, expr: "(a=x or b=y) and c>true"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr := tc.filter.String()
+			if expr != tc.expr {
+				t.Errorf("expected %q, got %q", tc.expr, expr)
+			}
+			again, err := ParseFilter(expr)
+			if err != nil {
+				t.Fatalf("failed to parse back: %+v", err)
+			}
+			if !reflect.DeepEqual(again, tc.filter) {
+				t.Errorf("expected %q to parse back to %+v, got %+v", expr, tc.filter, again)
+			}
+		})
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		expr   string
+		pos    int
+		column int
+		msg    string
+	}{
+		{name: "missing value", expr: "level=", pos: 6, column: 7, msg: "expected a value"},
+		{name: "keyword for value", expr: "level=and x=1", pos: 6, column: 7, msg: "expected a value"},
+		{name: "missing operator", expr: "level debug", pos: 6, column: 7, msg: "expected an operator"},
+		{name: "missing field", expr: "=debug", pos: 0, column: 1, msg: "expected a field"},
+		{name: "empty field", expr: `""=debug`, pos: 0, column: 1, msg: "empty name"},
+		{name: "trailing", expr: "level=debug)", pos: 11, column: 12, msg: "expected and, or or the end"},
+		{name: "unclosed paren", expr: "(level=debug", pos: 12, column: 13, msg: "to close ( at column 1"},
+		{name: "unterminated string", expr: `message="open`, pos: 8, column: 9, msg: "unterminated string"},
+		{name: "invalid escape", expr: `message="\q"`, pos: 8, column: 9, msg: "invalid escape"},
+		{name: "bad regex", expr: `message~"(["`, pos: 8, column: 9, msg: "invalid regex"},
+		{name: "null ordered", expr: "worker_id>null", pos: 10, column: 11, msg: "null can only"},
+		// columns count runes, positions bytes
+		{name: "multibyte field", expr: `名前="x" and =y`, pos: 15, column: 12, msg: "expected a field"},
+		{name: "multibyte unterminated", expr: `名="日本`, pos: 4, column: 3, msg: "unterminated string"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseFilter(tc.expr)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Pos != tc.pos || syntaxErr.Column() != tc.column {
+				t.Errorf("expected position %d, column %d, got %d, %d", tc.pos, tc.column, syntaxErr.Pos, syntaxErr.Column())
+			}
+			if !strings.Contains(err.Error(), tc.msg) {
+				t.Errorf("expected error containing %q, got %q", tc.msg, err)
+			}
+		})
+	}
+}
@@ -1,5 +1,13 @@
 package parcours
 
+import (
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
 // FilterOp represents a filter operation type.
 type FilterOp int
 
@@ -20,6 +28,9 @@ const (
 	Match    // regex match
 )
 
+// filterOps name ops, as they're serialized
+var filterOps = []string{"and", "or", "not", "eq", "ne", "gt", "gte", "lt", "lte", "contains", "match"}
+
 // Filter represents a composable filter for log queries.
 // Filters can be simple comparisons or complex logical combinations.
 type Filter struct {
@@ -35,6 +46,137 @@ type Sort struct {
 	Desc  bool   // Sort descending if true, ascending if false
 }
 
+// String names the op.
+func (op FilterOp) String() string {
+	if op < 0 || int(op) >= len(filterOps) {
+		return ""
+	}
+	return filterOps[op]
+}
+
+// ParseFilterOp from its name.
+func ParseFilterOp(name string) (op FilterOp, err error) {
+
+	idx := slices.Index(filterOps, name)
+	if idx < 0 {
+		err = errors.Errorf("unknown filter op %q", name)
+		return
+	}
+	return FilterOp(idx), nil
+}
+
+// MarshalYAML as the op's name.
+func (op FilterOp) MarshalYAML() (any, error) {
+	return op.String(), nil
+}
+
+// UnmarshalYAML from an op's name.
+func (op *FilterOp) UnmarshalYAML(node *yaml.Node) (err error) {
+
+	var name string
+	err = node.Decode(&name)
+	if err != nil {
+		return
+	}
+	*op, err = ParseFilterOp(name)
+	return
+}
+
+// MarshalYAML in a stable form, ops by name and levels as theirs, with
+// the type of values that wouldn't otherwise come back as they were.
+func (filter Filter) MarshalYAML() (any, error) {
+
+	fy := filterYAML{Op: filter.Op, Field: filter.Field, Value: filter.Value, Children: filter.Children}
+	switch val := filter.Value.(type) {
+	case Level:
+		fy.Value, fy.Type = val.String(), levelValue
+	case float64:
+		fy.Type = floatValue
+	case time.Time:
+		fy.Value, fy.Type = val.Format(time.RFC3339Nano), timeValue
+	}
+	return fy, nil
+}
+
+// UnmarshalYAML from its stable form, numbers as int64 or float64 and
+// values of a type as that type.
+func (filter *Filter) UnmarshalYAML(node *yaml.Node) (err error) {
+
+	var fy filterYAML
+	err = node.Decode(&fy)
+	if err != nil {
+		return
+	}
+
+	*filter = Filter{Op: fy.Op, Field: fy.Field, Value: fy.Value, Children: fy.Children}
+	switch val := fy.Value.(type) {
+	case int:
+		filter.Value = int64(val)
+	case time.Time:
+		filter.Value = val.UTC()
+	}
+
+	switch fy.Type {
+	case "":
+	case levelValue:
+		filter.Value = NormalizeLevel(fy.Value)
+	case floatValue:
+		filter.Value, err = toFloat(filter.Value)
+	case timeValue:
+		filter.Value, err = toTime(filter.Value)
+	default:
+		err = errors.Errorf("unknown value type %q", fy.Type)
+	}
+	err = errors.Wrapf(err, "failed to decode value of %s at line %d", fy.Field, node.Line)
+	return
+}
+
+// unexported
+
+// filterYAML is the serialized form of a Filter
+type filterYAML struct {
+	Op       FilterOp  `yaml:"op"`
+	Field    string    `yaml:"field,omitempty"`
+	Value    any       `yaml:"value,omitempty"`
+	Type     string    `yaml:"type,omitempty"`
+	Children []*Filter `yaml:"children,omitempty"`
+}
+
+// types of serialized values
+const (
+	levelValue = "level"
+	floatValue = "float"
+	timeValue  = "time"
+)
+
+// toFloat is a serialized number as a float
+func toFloat(val any) (num float64, err error) {
+
+	switch val := val.(type) {
+	case int64:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	}
+	err = errors.Errorf("expected a number, got %v", val)
+	return
+}
+
+// toTime is a serialized timestamp as a time, in UTC
+func toTime(val any) (ts time.Time, err error) {
+
+	switch val := val.(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		ts, err = time.Parse(time.RFC3339Nano, val)
+		err = errors.Wrapf(err, "failed to parse timestamp")
+		return ts.UTC(), err
+	}
+	err = errors.Errorf("expected a timestamp, got %v", val)
+	return
+}
+
 /*
 // Helper constructors for common filter operations
 
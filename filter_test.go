@@ -0,0 +1,93 @@
+package parcours
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFilterYAML(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		filter Filter
+	}{
+		{name: "zero", filter: Filter{}},
+		{name: "string", filter: Filter{Op: Eq, Field: "app", Value: "tag"}},
+		{name: "string like a timestamp", filter: Filter{Op: Eq, Field: "s", Value: "2025-11-13T20:30:00Z"}},
+		{name: "string like a number", filter: Filter{Op: Eq, Field: "s", Value: "12"}},
+		{name: "null", filter: Filter{Op: Ne, Field: "worker_id"}},
+		{name: "int", filter: Filter{Op: Gt, Field: "n", Value: int64(3)}},
+		{name: "whole float", filter: Filter{Op: Gt, Field: "n", Value: 3.0}},
+		{name: "float", filter: Filter{Op: Lt, Field: "n", Value: -1.5}},
+		{name: "bool", filter: Filter{Op: Eq, Field: "ok", Value: false}},
+		{name: "level", filter: Filter{Op: Gte, Field: "level", Value: LevelWarn}},
+		{name: "time", filter: Filter{Op: Gt, Field: "timestamp", Value: time.Date(2025, 11, 13, 20, 30, 0, 5, time.UTC)}},
+		{name: "nested", filter: Filter{Op: Or, Children: []*Filter{
+			{Op: Not, Children: []*Filter{{Op: Match, Field: "message", Value: "^shut"}}},
+			{Op: And, Children: []*Filter{
+				{Op: Eq, Field: "level", Value: LevelError},
+				{Op: Lte, Field: "timestamp", Value: time.Date(2025, 11, 13, 21, 0, 0, 0, time.UTC)},
+			}},
+		}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := yaml.Marshal(tc.filter)
+			if err != nil {
+				t.Fatalf("failed to marshal: %+v", err)
+			}
+			var filter Filter
+			err = yaml.Unmarshal(data, &filter)
+			if err != nil {
+				t.Fatalf("failed to unmarshal: %+v", err)
+			}
+			if !reflect.DeepEqual(filter, tc.filter) {
+				t.Errorf("expected %#v after round trip through\n%s\ngot %#v", tc.filter, data, filter)
+			}
+		})
+	}
+}
+
+func TestFilterYAMLTyped(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		yaml     string
+		expected any
+	}{
+		{name: "time in another zone", yaml: "op: gt\nfield: ts\nvalue: 2025-11-13T21:30:00+01:00\ntype: time\n", expected: time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC)},
+		{name: "quoted time", yaml: "op: gt\nfield: ts\nvalue: \"2025-11-13T20:30:00Z\"\ntype: time\n", expected: time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC)},
+		{name: "unquoted time", yaml: "op: gt\nfield: ts\nvalue: 2025-11-13T20:30:00Z\n", expected: time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC)},
+		{name: "level alias", yaml: "op: eq\nfield: level\nvalue: WARNING\ntype: level\n", expected: LevelWarn},
+		{name: "int as float", yaml: "op: eq\nfield: n\nvalue: 2\ntype: float\n", expected: 2.0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var filter Filter
+			err := yaml.Unmarshal([]byte(tc.yaml), &filter)
+			if err != nil {
+				t.Fatalf("failed to unmarshal: %+v", err)
+			}
+			if !reflect.DeepEqual(filter.Value, tc.expected) {
+				t.Errorf("expected %#v, got %#v", tc.expected, filter.Value)
+			}
+		})
+	}
+
+	for _, bad := range []string{
+		"op: gt\nfield: ts\nvalue: yesterday\ntype: time\n",
+		"op: eq\nfield: n\nvalue: two\ntype: float\n",
+		"op: eq\nfield: n\nvalue: 2\ntype: complex\n",
+	} {
+		var filter Filter
+		err := yaml.Unmarshal([]byte(bad), &filter)
+		if err == nil {
+			t.Errorf("expected error unmarshalling %q", bad)
+		}
+	}
+}
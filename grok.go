@@ -0,0 +1,106 @@
+package parcours
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// grokPatterns is a subset of the logstash grok library, adapted to RE2.
+var grokPatterns = map[string]string{
+	"USERNAME":     `[a-zA-Z0-9._-]+`,
+	"USER":         `%{USERNAME}`,
+	"INT":          `[+-]?[0-9]+`,
+	"POSINT":       `[1-9][0-9]*`,
+	"NONNEGINT":    `[0-9]+`,
+	"NUMBER":       `[+-]?(?:[0-9]+(?:\.[0-9]*)?|\.[0-9]+)`,
+	"BASE16NUM":    `(?:0[xX])?[0-9a-fA-F]+`,
+	"WORD":         `\w+`,
+	"NOTSPACE":     `\S+`,
+	"SPACE":        `\s*`,
+	"DATA":         `.*?`,
+	"GREEDYDATA":   `.*`,
+	"QUOTEDSTRING": `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"QS":           `%{QUOTEDSTRING}`,
+	"UUID":         `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+
+	"IPV4":     `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]{1,2})\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]{1,2})`,
+	"IPV6":     `[0-9A-Fa-f]{0,4}(?::[0-9A-Fa-f]{0,4}){2,7}`,
+	"IP":       `%{IPV6}|%{IPV4}`,
+	"HOSTNAME": `[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?`,
+	"IPORHOST": `%{IP}|%{HOSTNAME}`,
+	"HOSTPORT": `%{IPORHOST}:%{POSINT}`,
+
+	"URIPATH":      `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":     `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM": `%{URIPATH}(?:%{URIPARAM})?`,
+
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]un(?:e)?|[Jj]ul(?:y)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"MONTHNUM":          `0?[1-9]|1[0-2]`,
+	"MONTHDAY":          `(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9]`,
+	"DAY":               `(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)`,
+	"YEAR":              `\d\d(?:\d\d)?`,
+	"HOUR":              `2[0123]|[01]?[0-9]`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `Z|[+-]%{HOUR}(?::?%{MINUTE})`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?(?:%{ISO8601_TIMEZONE})?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"TZ":                `[A-Z]{3,4}`,
+
+	"PROG":        `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG":  `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+	"SYSLOGHOST":  `%{IPORHOST}`,
+	"LOGLEVEL":    `[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo(?:rmation)?|INFO(?:RMATION)?|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|[Pp]anic|PANIC|[Ee]merg(?:ency)?|EMERG(?:ENCY)?|LOG|STATEMENT|DETAIL|HINT`,
+	"HTTPVERSION": `[0-9]+(?:\.[0-9]+)?`,
+	"HTTPMETHOD":  `GET|HEAD|POST|PUT|DELETE|CONNECT|OPTIONS|TRACE|PATCH`,
+}
+
+var grokRef = regexp.MustCompile(`%\{(\w+)(?::([\w.-]+))?\}`)
+
+// expandGrok rewrites a grok expression as a regular expression,
+// turning %{PATTERN:field} references into named capture groups.
+func expandGrok(expr string, custom map[string]string, depth int) (re string, err error) {
+
+	if depth > 16 {
+		err = errors.Errorf("grok expansion too deep, recursive pattern?")
+		return
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range grokRef.FindAllStringSubmatchIndex(expr, -1) {
+		b.WriteString(expr[last:loc[0]])
+		last = loc[1]
+
+		name := expr[loc[2]:loc[3]]
+		pattern, ok := custom[name]
+		if !ok {
+			pattern, ok = grokPatterns[name]
+		}
+		if !ok {
+			err = errors.Errorf("unknown grok pattern: %s", name)
+			return
+		}
+
+		var inner string
+		inner, err = expandGrok(pattern, custom, depth+1)
+		if err != nil {
+			return
+		}
+
+		if loc[4] < 0 {
+			b.WriteString("(?:" + inner + ")")
+			continue
+		}
+		field := strings.NewReplacer(".", "_", "-", "_").Replace(expr[loc[4]:loc[5]])
+		b.WriteString("(?P<" + field + ">" + inner + ")")
+	}
+	b.WriteString(expr[last:])
+
+	re = b.String()
+	return
+}
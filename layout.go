@@ -2,12 +2,27 @@ package parcours
 
 import (
 	"os"
+	"time"
+	"unicode/utf8"
 
+	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
 )
 
 type Layout struct {
 	Columns []Column `yaml:"columns"`
+	// Timezone for timestamps, UTC, Local or a named zone, UTC if empty
+	Timezone  string     `yaml:"timezone,omitempty"`
+	Parsers   []Parser   `yaml:"parsers,omitempty"`
+	Multiline *Multiline `yaml:"multiline,omitempty"`
+	// Correlate names fields whose values tie lines together, such as request ids
+	Correlate []string `yaml:"correlate,omitempty"`
+	// Compare names fields whose values are compared across views
+	Compare []string `yaml:"compare,omitempty"`
+	// Timeline names the field lines are counted by over time, level if empty
+	Timeline string `yaml:"timeline,omitempty"`
+
+	zones []*time.Location
 }
 
 func LoadLayout(path string) (*Layout, error) {
@@ -21,5 +36,98 @@ func LoadLayout(path string) (*Layout, error) {
 		return nil, err
 	}
 
+	layout.zones, err = layout.resolveZones()
+	if err != nil {
+		return nil, err
+	}
+
 	return &layout, nil
 }
+
+// Zones returns the timezones to cycle through when displaying timestamps,
+// starting with the layout's own and including any named by columns.
+// They are resolved once, when loaded or first asked for.
+func (layout *Layout) Zones() []*time.Location {
+
+	if layout.zones == nil {
+		// invalid zones are refused when loading
+		layout.zones, _ = layout.resolveZones()
+	}
+	return layout.zones
+}
+
+// Location of the named zone among the layout's, UTC if not among them.
+func (layout *Layout) Location(name string) *time.Location {
+
+	for _, loc := range layout.Zones() {
+		if loc.String() == name {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// Decoder creates a decoder for the named parser, or for ndjson when name is empty.
+func (layout *Layout) Decoder(name string) (dec *Decoder, err error) {
+
+	if name == "" || name == "json" {
+		return NewDecoder(nil, layout.Multiline)
+	}
+
+	for i := range layout.Parsers {
+		if layout.Parsers[i].Name == name {
+			return NewDecoder(&layout.Parsers[i], layout.Multiline)
+		}
+	}
+
+	err = errors.Errorf("no parser named %s in layout", name)
+	return
+}
+
+// maxAutoWidth caps the width of columns in an AutoLayout
+const maxAutoWidth = 40
+
+// AutoLayout lays out a column per field, wide enough for its name and values.
+func AutoLayout(fields []Field, lines []Line) *Layout {
+
+	layout := &Layout{Columns: make([]Column, len(fields))}
+	for i, field := range fields {
+		width := utf8.RuneCountInString(field.Name)
+		for _, line := range lines {
+			width = max(width, utf8.RuneCountInString(formatValue(line[i], field.Type, "", time.UTC)))
+		}
+		layout.Columns[i] = Column{Field: field.Name, Width: min(width, maxAutoWidth) + 2}
+	}
+	return layout
+}
+
+// unexported
+
+// resolveZones to locations, skipping any invalid
+func (layout *Layout) resolveZones() (zones []*time.Location, err error) {
+
+	seen := map[string]bool{}
+	add := func(zone string) {
+		if zone == "" || seen[zone] {
+			return
+		}
+		seen[zone] = true
+
+		loc, loadErr := time.LoadLocation(zone)
+		if loadErr != nil {
+			if err == nil {
+				err = errors.Wrapf(loadErr, "invalid timezone in layout")
+			}
+			return
+		}
+		zones = append(zones, loc)
+	}
+
+	add(layout.Timezone)
+	add("UTC")
+	add("Local")
+	for _, col := range layout.Columns {
+		add(col.Timezone)
+	}
+	return
+}
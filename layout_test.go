@@ -0,0 +1,44 @@
+package parcours
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLayoutZones(t *testing.T) {
+
+	layout := &Layout{
+		Timezone: "Asia/Tokyo",
+		Columns:  []Column{{Field: "timestamp", Timezone: "Europe/Paris"}, {Field: "at", Timezone: "UTC"}},
+	}
+
+	var names []string
+	for _, loc := range layout.Zones() {
+		names = append(names, loc.String())
+	}
+	expected := []string{"Asia/Tokyo", "UTC", "Local", "Europe/Paris"}
+	if !slices.Equal(names, expected) {
+		t.Fatalf("expected zones %v, got %v", expected, names)
+	}
+
+	// resolved once, so the same locations come back
+	if paris := layout.Location("Europe/Paris"); paris != layout.Zones()[3] {
+		t.Errorf("expected Europe/Paris resolved with the layout, got %v", paris)
+	}
+	if loc := layout.Location("Mars/Olympus"); loc != time.UTC {
+		t.Errorf("expected UTC for a zone not in the layout, got %v", loc)
+	}
+
+	path := filepath.Join(t.TempDir(), "layout.yaml")
+	err := os.WriteFile(path, []byte("timezone: Mars/Olympus\n"), 0644)
+	if err != nil {
+		t.Fatalf("failed to write layout: %+v", err)
+	}
+	_, err = LoadLayout(path)
+	if err == nil {
+		t.Errorf("expected error loading a layout with an invalid timezone")
+	}
+}
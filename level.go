@@ -0,0 +1,89 @@
+package parcours
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Level is a canonical log level, ordered by severity.
+type Level int
+
+const (
+	LevelUnknown Level = iota
+	LevelTrace
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+// Levels are the canonical level names in order of severity.
+var Levels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+// levelAliases maps the spellings used by common logging libraries.
+var levelAliases = map[string]Level{
+	"trace": LevelTrace, "trc": LevelTrace, "t": LevelTrace, "verbose": LevelTrace,
+	"debug": LevelDebug, "dbg": LevelDebug, "d": LevelDebug,
+	"info": LevelInfo, "inf": LevelInfo, "i": LevelInfo, "information": LevelInfo, "notice": LevelInfo,
+	"warn": LevelWarn, "warning": LevelWarn, "wrn": LevelWarn, "w": LevelWarn,
+	"error": LevelError, "err": LevelError, "e": LevelError, "eror": LevelError,
+	"fatal": LevelFatal, "ftl": LevelFatal, "f": LevelFatal, "crit": LevelFatal, "critical": LevelFatal,
+	"alert": LevelFatal, "emerg": LevelFatal, "emergency": LevelFatal,
+	"panic": LevelFatal, "dpanic": LevelFatal,
+}
+
+// String returns the canonical name, empty when unknown.
+func (lvl Level) String() string {
+	if lvl <= LevelUnknown || int(lvl) > len(Levels) {
+		return ""
+	}
+	return Levels[lvl-1]
+}
+
+// NormalizeLevel maps a level as logged onto a canonical level.
+// Numeric levels follow pino/bunyan: 10 trace, 20 debug .. 60 fatal.
+func NormalizeLevel(val any) Level {
+
+	switch val := val.(type) {
+	case Level:
+		return val
+	case json.Number:
+		return NormalizeLevel(string(val))
+	case float64:
+		return numericLevel(val)
+	case int:
+		return numericLevel(float64(val))
+	case int64:
+		return numericLevel(float64(val))
+	case string:
+		str := strings.ToLower(strings.TrimSpace(val))
+		if lvl, ok := levelAliases[str]; ok {
+			return lvl
+		}
+		if num, err := strconv.ParseFloat(str, 64); err == nil {
+			return numericLevel(num)
+		}
+	}
+	return LevelUnknown
+}
+
+func numericLevel(num float64) Level {
+
+	switch {
+	case num <= 0:
+		return LevelUnknown
+	case num <= 10:
+		return LevelTrace
+	case num <= 20:
+		return LevelDebug
+	case num <= 30:
+		return LevelInfo
+	case num <= 40:
+		return LevelWarn
+	case num <= 50:
+		return LevelError
+	}
+	return LevelFatal
+}
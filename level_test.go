@@ -0,0 +1,56 @@
+package parcours
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeLevel(t *testing.T) {
+
+	tests := []struct {
+		val      any
+		expected Level
+	}{
+		{val: "info", expected: LevelInfo},
+		{val: " WARNING ", expected: LevelWarn},
+		{val: "Err", expected: LevelError},
+		{val: "dpanic", expected: LevelFatal},
+		{val: "notice", expected: LevelInfo},
+		{val: "verbose", expected: LevelTrace},
+		{val: "loud", expected: LevelUnknown},
+		{val: "", expected: LevelUnknown},
+		{val: json.Number("30"), expected: LevelInfo},
+		{val: "50", expected: LevelError},
+		{val: 10.0, expected: LevelTrace},
+		{val: 15, expected: LevelDebug},
+		{val: int64(40), expected: LevelWarn},
+		{val: 60, expected: LevelFatal},
+		{val: 0, expected: LevelUnknown},
+		{val: LevelDebug, expected: LevelDebug},
+		{val: true, expected: LevelUnknown},
+		{val: nil, expected: LevelUnknown},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("%T %v", tc.val, tc.val), func(t *testing.T) {
+			if lvl := NormalizeLevel(tc.val); lvl != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, lvl)
+			}
+		})
+	}
+}
+
+func TestLevelString(t *testing.T) {
+
+	for i, name := range Levels {
+		if lvl := Level(i + 1); lvl.String() != name || NormalizeLevel(name) != lvl {
+			t.Errorf("expected level %d named %s both ways, got %q", lvl, name, lvl.String())
+		}
+	}
+	for _, lvl := range []Level{LevelUnknown, LevelFatal + 1, -1} {
+		if name := lvl.String(); name != "" {
+			t.Errorf("expected level %d unnamed, got %q", lvl, name)
+		}
+	}
+}
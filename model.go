@@ -1,18 +1,28 @@
 package parcours
 
 import (
+	"context"
 	"encoding/json"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/pkg/errors"
 )
 
 // Model is the bubbletea model for the log viewer TUI.
 type Model struct {
 	Store  Store
 	Layout *Layout
+	// Tail of lines from a followed file, when following
+	Tail <-chan Line
 
 	// View data
+	Filter     Filter
+	Sorts      []Sort
 	Fields     []Field
 	Lines      []Line
 	TotalLines int
@@ -24,13 +34,162 @@ type Model struct {
 	Height       int
 	ShowFull     bool
 	FullRecord   map[string]any
+	Zone         *time.Location
+	// Progress of a load or promotion, for stores reporting it
+	Progress Progress
+	// Prompt for input, when open
+	Prompt *Prompt
+	// Results of a query, shown in place of the view until closed
+	Results *Results
+	// Surround matches with lines of context, for stores that can
+	Surround Surround
+	// Differences between views compared, shown in place of the view until closed
+	Differences *Differences
+	// Templates of the patterns in view, shown in place of it until closed
+	Templates *Templates
+	// Timeline of the view, shown above it when open
+	Timeline *Timeline
+	// SelectedColumn among the visible columns
+	SelectedColumn int
+	// Summary of the selected column, shown below the view when open
+	Summary *Summary
+	// Bookmarks of lines, marked in the view, when kept
+	Bookmarks *Bookmarks
+	// Columns shown in order, the layout's when empty
+	Columns []string
+	// Views saved, when kept
+	Views *Views
+	// ViewMenu of saved views, shown in place of the view until closed
+	ViewMenu *ViewMenu
+	// Crumbs of views pivoted through, the current one last, empty for the first
+	Crumbs []Crumb
+	// Err from the last action, shown until the next key
+	Err error
+
+	prompting promptKind
+	// filtering by an expression submitted, until the store takes it
+	filtering bool
+	loads     *loader
+	fetches   *loader
+	// lines around the page, starting at bufferStart in the view
+	buffer      []Line
+	bufferStart int
+}
+
+const (
+	pageSize = 20
+	// lines kept around the page, fetched a chunk at a time as it nears an end
+	bufferSize       = 5 * pageSize
+	chunkSize        = 2 * pageSize
+	progressInterval = 250 * time.Millisecond
+)
+
+// loader hands out a context per load, cancelling the one it supersedes,
+// and is shared by copies of a Model.
+type loader struct {
+	ctx     context.Context
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	seq     int
+	pending bool
 }
 
+// next cancels the current load and starts another
+func (ldr *loader) next() (ctx context.Context, seq int) {
+
+	ldr.mu.Lock()
+	defer ldr.mu.Unlock()
+
+	if ldr.cancel != nil {
+		ldr.cancel()
+	}
+	ctx, ldr.cancel = context.WithCancel(ldr.ctx)
+	ldr.seq++
+	ldr.pending = true
+	return ctx, ldr.seq
+}
+
+// finish reports whether seq is the latest load, which is then no longer pending
+func (ldr *loader) finish(seq int) bool {
+
+	ldr.mu.Lock()
+	defer ldr.mu.Unlock()
+
+	if seq != ldr.seq {
+		return false
+	}
+	ldr.pending = false
+	return true
+}
+
+// stop cancels the current load, its results being stale
+func (ldr *loader) stop() {
+
+	ldr.mu.Lock()
+	defer ldr.mu.Unlock()
+
+	if ldr.cancel != nil {
+		ldr.cancel()
+	}
+	ldr.seq++
+	ldr.pending = false
+}
+
+// busy reports whether a load is pending
+func (ldr *loader) busy() bool {
+
+	ldr.mu.Lock()
+	defer ldr.mu.Unlock()
+	return ldr.pending
+}
+
+// promptKind is what an open prompt is for
+type promptKind int
+
+const (
+	queryPrompt promptKind = iota + 1
+	statsPrompt
+	notePrompt
+	viewPrompt
+	filterPrompt
+)
+
+// edge of the view jumped to by a load
+type edge int
+
+const (
+	noEdge edge = iota
+	topEdge
+	bottomEdge
+)
+
 type loadDataMsg struct {
 	fields []Field
 	lines  []Line
+	start  int
 	count  int
 	err    error
+	seq    int
+	anchor string
+	at     int
+	edge   edge
+}
+
+type fetchMsg struct {
+	lines  []Line
+	before bool
+	from   string
+	size   int
+	err    error
+	seq    int
+}
+
+type tailMsg struct {
+	closed bool
+}
+
+type progressMsg struct {
+	progress Progress
 }
 
 type fullRecordMsg struct {
@@ -38,63 +197,423 @@ type fullRecordMsg struct {
 	err  error
 }
 
-// NewModel creates a new TUI model with the given store.
-func NewModel(store Store) Model {
-	layout, err := LoadLayout("layout.yaml")
-	if err != nil {
-		panic(err) // TODO: handle better
-	}
+// NewModel creates a new TUI model with the given store and layout,
+// queries lasting no longer than ctx, promoting the fields of its columns.
+func NewModel(ctx context.Context, store Store, layout *Layout) (m Model, err error) {
 
-	// Promote fields from layout
-	// TODO: improve error handling/logging so we can see promotion failures
 	for _, col := range layout.Columns {
 		// Skip demoted fields
 		if col.Demote {
 			continue
 		}
 		// Skip base fields that already exist
-		if col.Field == "timestamp" || col.Field == "message" {
+		if col.Field == "timestamp" || col.Field == "level" || col.Field == "message" {
 			continue
 		}
-		if err := store.Promote(col.Field); err != nil {
-			// TODO: log error instead of panicking
-			panic(err)
+		err = store.Promote(ctx, col.Field)
+		if err != nil {
+			return
 		}
 	}
 
-	return Model{
-		Store:  store,
-		Layout: layout,
+	m = Model{
+		Store:   store,
+		Layout:  layout,
+		Zone:    layout.Zones()[0],
+		loads:   &loader{ctx: ctx},
+		fetches: &loader{ctx: ctx},
 	}
+	return
 }
 
 func (m Model) Init() tea.Cmd {
-	return m.loadData()
+	cmds := []tea.Cmd{m.loadData(), m.watchProgress()}
+	if m.Tail != nil {
+		cmds = append(cmds, m.waitTail())
+	}
+	return tea.Batch(cmds...)
+}
+
+// watchProgress polls for progress, when the store reports it
+func (m Model) watchProgress() tea.Cmd {
+	reporter, ok := m.Store.(Reporter)
+	if !ok {
+		return nil
+	}
+	return tea.Tick(progressInterval, func(time.Time) tea.Msg {
+		return progressMsg{progress: reporter.Progress()}
+	})
+}
+
+// waitTail waits for tailed lines, draining any already queued
+func (m Model) waitTail() tea.Cmd {
+	return func() tea.Msg {
+		_, ok := <-m.Tail
+		if !ok {
+			return tailMsg{closed: true}
+		}
+		for {
+			select {
+			case _, ok := <-m.Tail:
+				if !ok {
+					return tailMsg{closed: true}
+				}
+			default:
+				return tailMsg{}
+			}
+		}
+	}
 }
 
+// loadData loads the page at the scroll offset
 func (m Model) loadData() tea.Cmd {
+	return m.load("", noEdge)
+}
+
+// reload the page, keeping to the selected line as rows come and go
+func (m Model) reload() tea.Cmd {
+	return m.load(m.selectedID(), noEdge)
+}
+
+// load the view and buffer, from the first line buffered or an edge,
+// cancelling any load or fetch in flight, their results being stale
+func (m Model) load(anchor string, edge edge) tea.Cmd {
+	ctx, seq := m.loads.next()
+	m.fetches.stop()
+
+	from, start, total := "", m.bufferStart, m.TotalLines
+	if len(m.buffer) > 0 && edge == noEdge {
+		from = m.buffer[0].ID(m.Fields)
+	}
+
 	return func() tea.Msg {
-		fields, count, err := m.Store.GetView()
+		fields, count, err := m.Store.GetView(ctx)
 		if err != nil {
-			return loadDataMsg{err: err}
+			return loadDataMsg{err: err, seq: seq}
 		}
 
-		lines, err := m.Store.GetPage(m.ScrollOffset, 20)
+		var lines []Line
+		seeker, seeks := m.Store.(Seeker)
+		switch {
+		case seeks && edge != noEdge:
+			lines, err = seeker.GetPageAt(ctx, Cursor{Before: edge == bottomEdge}, bufferSize)
+		case seeks && from != "":
+			// keep to the first line buffered, wherever it's got to
+			lines, err = seeker.GetPageAt(ctx, Cursor{ID: from, Inclusive: true}, bufferSize)
+		case edge == topEdge:
+			start = 0
+			lines, err = m.Store.GetPage(ctx, start, bufferSize)
+		case edge == bottomEdge:
+			start = max(count-bufferSize, 0)
+			lines, err = m.Store.GetPage(ctx, start, bufferSize)
+		}
 		if err != nil {
-			return loadDataMsg{err: err}
+			return loadDataMsg{err: err, seq: seq}
+		}
+
+		switch {
+		case edge == topEdge:
+			start = 0
+		case edge == bottomEdge:
+			start = max(count-len(lines), 0)
+		case len(lines) > 0:
+			start = max(min(start, count-len(lines)), 0)
+		default:
+			// by offset, shifted back by rows gone
+			start = max(min(start-max(total-count, 0), count-bufferSize), 0)
+			lines, err = m.Store.GetPage(ctx, start, bufferSize)
+			if err != nil {
+				return loadDataMsg{err: err, seq: seq}
+			}
 		}
 
 		return loadDataMsg{
 			fields: fields,
 			lines:  lines,
+			start:  start,
 			count:  count,
+			seq:    seq,
+			anchor: anchor,
+			at:     -1,
+			edge:   edge,
+		}
+	}
+}
+
+// setView changes the store's view, keeping the selected line, or its
+// nearest neighbour in the new view, on the same row of the page
+func (m Model) setView(filter Filter, sorts []Sort) (Model, tea.Cmd) {
+	m.Filter, m.Sorts = filter, sorts
+	cmd := m.relocate(func(ctx context.Context) error {
+		return m.Store.SetView(ctx, filter, sorts)
+	})
+	if m.Timeline != nil {
+		// counted once the view is set
+		cmd = tea.Sequence(cmd, m.timeline())
+	}
+	if m.Summary != nil {
+		cmd = tea.Sequence(cmd, m.summarize(m.Summary.By))
+	}
+	return m, cmd
+}
+
+// submitFilter parses the expression submitted and pivots to the lines it
+// matches, leaving the prompt open until the store takes it, with the
+// cursor at any syntax error
+func (m Model) submitFilter(expr string) (Model, tea.Cmd) {
+
+	filter, err := ParseFilter(expr)
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		m.Prompt.Err = err
+		m.Prompt.MoveTo(syntaxErr.Column() - 1)
+		return m, nil
+	}
+
+	label := filter.String()
+	if label == "" {
+		label = "all"
+	}
+	m.filtering = true
+	return m.pivot(label, filter, m.Sorts)
+}
+
+// surround matches with lines of context, when the store can
+func (m Model) surround(lines int) (Model, tea.Cmd) {
+	surrounder, ok := m.Store.(Surrounder)
+	if !ok {
+		return m, nil
+	}
+
+	m.Surround = Surround{Lines: max(lines, 0), By: "timestamp"}
+	surround := m.Surround
+	return m, m.relocate(func(ctx context.Context) error {
+		return surrounder.SetSurround(ctx, surround)
+	})
+}
+
+// relocate the selected line after change alters the view
+func (m Model) relocate(change func(ctx context.Context) error) tea.Cmd {
+	return m.locate(m.selectedID(), change)
+}
+
+// jump to the line with id, or its nearest neighbour in view
+func (m Model) jump(id string) tea.Cmd {
+	return m.locate(id, nil)
+}
+
+// locate the line with id, after any change to the view, loading the
+// buffer around it and selecting it on the same row of the page
+func (m Model) locate(id string, change func(ctx context.Context) error) tea.Cmd {
+	ctx, seq := m.loads.next()
+	m.fetches.stop()
+
+	return func() tea.Msg {
+		if change != nil {
+			err := change(ctx)
+			if err != nil {
+				return loadDataMsg{err: err, seq: seq}
+			}
+		}
+
+		fields, count, err := m.Store.GetView(ctx)
+		if err != nil {
+			return loadDataMsg{err: err, seq: seq}
+		}
+
+		at := 0
+		if id != "" {
+			at, err = m.Store.Locate(ctx, id)
+			if err != nil {
+				return loadDataMsg{err: err, seq: seq}
+			}
+		}
+
+		lines, start, err := m.around(ctx, id, at, count)
+		if err != nil {
+			return loadDataMsg{err: err, seq: seq}
+		}
+
+		return loadDataMsg{
+			fields: fields,
+			lines:  lines,
+			start:  start,
+			count:  count,
+			seq:    seq,
+			anchor: id,
+			at:     at,
+		}
+	}
+}
+
+// around loads the buffer around the line with id at offset at, by cursor
+// either side of it when the store pages by it
+func (m Model) around(ctx context.Context, id string, at, count int) (lines []Line, start int, err error) {
+	seeker, ok := m.Store.(Seeker)
+	if !ok || id == "" {
+		start = max(min(at-bufferSize/2, count-bufferSize), 0)
+		lines, err = m.Store.GetPage(ctx, start, bufferSize)
+		return
+	}
+
+	before, err := seeker.GetPageAt(ctx, Cursor{ID: id, Before: true}, bufferSize/2)
+	if err != nil {
+		return
+	}
+	after, err := seeker.GetPageAt(ctx, Cursor{ID: id, Inclusive: true}, bufferSize/2)
+	if err != nil {
+		return
+	}
+
+	lines = slices.Concat(before, after)
+	start = max(at-len(before), 0)
+	if len(after) < bufferSize/2 {
+		// through to the end, where the line may have been located past
+		start = max(count-len(lines), 0)
+	}
+	return
+}
+
+// fetch a chunk of lines past an end of the buffer, by cursor when the
+// store pages by it, or else by offset
+func (m Model) fetch(before bool) tea.Cmd {
+	ctx, seq := m.fetches.next()
+
+	from := m.buffer[len(m.buffer)-1].ID(m.Fields)
+	offset, size := m.bufferStart+len(m.buffer), chunkSize
+	if before {
+		from = m.buffer[0].ID(m.Fields)
+		offset = max(m.bufferStart-chunkSize, 0)
+		size = m.bufferStart - offset
+	}
+
+	return func() tea.Msg {
+		var lines []Line
+		var err error
+		if seeker, ok := m.Store.(Seeker); ok {
+			lines, err = seeker.GetPageAt(ctx, Cursor{ID: from, Before: before}, size)
+		} else {
+			lines, err = m.Store.GetPage(ctx, offset, size)
+		}
+		return fetchMsg{lines: lines, before: before, from: from, size: size, err: err, seq: seq}
+	}
+}
+
+// prefetch lines past an end of the buffer when the page is within a
+// page of it, so that moving a line at a time rarely waits on the store
+func (m Model) prefetch() tea.Cmd {
+	if len(m.buffer) == 0 || m.loads.busy() || m.fetches.busy() {
+		return nil
+	}
+
+	top := m.ScrollOffset - m.bufferStart
+	switch {
+	case top+2*pageSize > len(m.buffer) && m.bufferStart+len(m.buffer) < m.TotalLines:
+		return m.fetch(false)
+	case top < pageSize && m.bufferStart > 0:
+		return m.fetch(true)
+	}
+	return nil
+}
+
+// extend the buffer with fetched lines, trimming the far end to size
+func (m Model) extend(msg fetchMsg) Model {
+	top := m.ScrollOffset - m.bufferStart
+
+	if msg.before {
+		m.buffer = slices.Concat(msg.lines, m.buffer)
+		m.bufferStart -= len(msg.lines)
+		top += len(msg.lines)
+		if len(msg.lines) < msg.size {
+			// reached the top
+			m.bufferStart = 0
+		}
+		m.buffer = m.buffer[:min(len(m.buffer), bufferSize)]
+	} else {
+		m.buffer = slices.Concat(m.buffer, msg.lines)
+		if len(msg.lines) < msg.size {
+			// reached the bottom
+			m.bufferStart = max(m.bufferStart, m.TotalLines-len(m.buffer))
+		}
+		if trim := len(m.buffer) - bufferSize; trim > 0 {
+			m.buffer = m.buffer[trim:]
+			m.bufferStart += trim
+			top -= trim
 		}
 	}
+
+	m.ScrollOffset = m.bufferStart + top
+	return m.page()
+}
+
+// page shows buffered lines at the scroll offset, keeping it and the
+// selection within the buffer
+func (m Model) page() Model {
+	top := max(min(m.ScrollOffset-m.bufferStart, len(m.buffer)-pageSize), 0)
+	m.ScrollOffset = m.bufferStart + top
+	m.Lines = m.buffer[top:min(top+pageSize, len(m.buffer))]
+	m.SelectedRow = max(min(m.SelectedRow, len(m.Lines)-1), 0)
+	return m
+}
+
+// move the selection by delta lines, as far as the buffer goes
+func (m Model) move(delta int) (Model, tea.Cmd) {
+	if len(m.buffer) == 0 {
+		return m, nil
+	}
+
+	at := m.ScrollOffset + m.SelectedRow + delta
+	at = max(min(at, m.bufferStart+len(m.buffer)-1), m.bufferStart)
+	switch {
+	case at < m.ScrollOffset:
+		m.ScrollOffset = at
+	case at >= m.ScrollOffset+pageSize:
+		m.ScrollOffset = at - pageSize + 1
+	}
+	m = m.page()
+	m.SelectedRow = at - m.ScrollOffset
+
+	cmd := m.prefetch()
+	if m.ShowFull {
+		cmd = tea.Batch(cmd, m.fetchFullRecord(m.selectedID()))
+	}
+	return m, cmd
+}
+
+// selectedID is the id of the selected line, if any
+func (m Model) selectedID() string {
+	if m.SelectedRow < 0 || m.SelectedRow >= len(m.Lines) {
+		return ""
+	}
+	return m.Lines[m.SelectedRow].ID(m.Fields)
+}
+
+// anchor the selection to a line after a load, or else to the offset
+// located for it unless negative
+func (m Model) anchor(id string, at int) Model {
+	for i, line := range m.buffer {
+		if line.ID(m.Fields) == id {
+			return m.selectAt(m.bufferStart + i)
+		}
+	}
+	if at < 0 {
+		return m
+	}
+	return m.selectAt(at)
+}
+
+// selectAt selects the line at offset at, keeping to the same row of the
+// page where it can
+func (m Model) selectAt(at int) Model {
+	m.ScrollOffset = at - m.SelectedRow
+	m = m.page()
+	m.SelectedRow = max(min(at-m.ScrollOffset, len(m.Lines)-1), 0)
+	return m
 }
 
 func (m Model) fetchFullRecord(id string) tea.Cmd {
 	return func() tea.Msg {
-		data, err := m.Store.GetJson(id)
+		data, err := m.Store.GetJson(m.loads.ctx, id)
 		return fullRecordMsg{data: data, err: err}
 	}
 }
@@ -102,14 +621,75 @@ func (m Model) fetchFullRecord(id string) tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case loadDataMsg:
+		if !m.loads.finish(msg.seq) {
+			// superseded
+			return m, nil
+		}
+		filtering := m.filtering
+		m.filtering = false
+		if msg.err != nil && filtering && m.Prompt != nil {
+			// back to the view before, for the expression to be fixed
+			m.Prompt.Err = msg.err
+			return m.back()
+		}
 		if msg.err != nil {
-			// TODO: handle error
+			m.Err = msg.err
 			return m, nil
 		}
+		if filtering {
+			m.Prompt, m.prompting = nil, 0
+		}
 		m.Fields = msg.fields
-		m.Lines = msg.lines
 		m.TotalLines = msg.count
-		return m, nil
+		m.buffer, m.bufferStart = msg.lines, msg.start
+
+		switch msg.edge {
+		case topEdge:
+			m.ScrollOffset, m.SelectedRow = 0, 0
+		case bottomEdge:
+			m.ScrollOffset, m.SelectedRow = msg.count, pageSize-1
+		}
+		m = m.page()
+		if msg.anchor != "" {
+			m = m.anchor(msg.anchor, msg.at)
+		}
+
+		cmd := tea.Batch(m.prefetch(), m.checkBookmarks())
+		if msg.edge != noEdge && m.ShowFull && len(m.Lines) > 0 {
+			cmd = tea.Batch(cmd, m.fetchFullRecord(m.selectedID()))
+		}
+		return m, cmd
+
+	case fetchMsg:
+		if !m.fetches.finish(msg.seq) || msg.err != nil || len(m.buffer) == 0 {
+			return m, nil
+		}
+		// the buffer's been reloaded since
+		edge := m.buffer[len(m.buffer)-1]
+		if msg.before {
+			edge = m.buffer[0]
+		}
+		if edge.ID(m.Fields) != msg.from {
+			return m, nil
+		}
+
+		m = m.extend(msg)
+		return m, m.prefetch()
+
+	case progressMsg:
+		// refresh while loading, so that pages are browsable as they arrive
+		wasActive := m.Progress.Active()
+		m.Progress = msg.progress
+		if (m.Progress.Active() && !m.loads.busy()) || (wasActive && !m.Progress.Active()) {
+			return m, tea.Batch(m.reload(), m.watchProgress())
+		}
+		return m, m.watchProgress()
+
+	case tailMsg:
+		if msg.closed {
+			return m, m.reload()
+		}
+		return m, tea.Batch(m.reload(), m.waitTail())
 
 	case fullRecordMsg:
 		if msg.err != nil {
@@ -121,72 +701,174 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.FullRecord = parseJsonFields(msg.data, m.Layout)
 		return m, nil
 
+	case queryMsg:
+		if msg.err != nil {
+			if m.Prompt != nil {
+				m.Prompt.Err = msg.err
+			}
+			return m, nil
+		}
+		m.Prompt, m.prompting = nil, 0
+		m.Results = NewResults(msg.query, msg.fields, msg.lines)
+		return m, nil
+
+	case compareMsg:
+		if msg.err != nil {
+			m.Err = msg.err
+			return m, nil
+		}
+		m.Differences = NewDifferences(msg.comparison)
+		return m, nil
+
+	case patternsMsg:
+		if msg.err != nil {
+			m.Err = msg.err
+			return m, nil
+		}
+		m.Templates = NewTemplates(msg.patterns)
+		return m, nil
+
+	case timelineMsg:
+		if msg.err != nil {
+			m.Err = msg.err
+			return m, nil
+		}
+		m.Timeline = &msg.timeline
+		return m, nil
+
+	case bookmarkMsg:
+		return m.setBookmark(msg)
+
+	case bookmarksCheckedMsg:
+		return m.checkedBookmarks(msg)
+
+	case summaryMsg:
+		if msg.err != nil {
+			m.Err = msg.err
+			return m, nil
+		}
+		m.Summary = &msg.summary
+		return m, nil
+
+	case correlateMsg:
+		if msg.err != nil {
+			m.Err = msg.err
+			return m, nil
+		}
+		return m.pivot(msg.label, msg.filter, []Sort{{Field: "timestamp"}})
+
+	case tea.PasteMsg:
+		if m.Prompt != nil {
+			m.Prompt.Insert(msg.Content)
+		}
+
 	case tea.KeyPressMsg:
+		m.Err = nil
+		if m.Prompt != nil {
+			return m.updatePrompt(msg)
+		}
+		if m.Results != nil {
+			return m.updateResults(msg)
+		}
+		if m.Differences != nil {
+			return m.updateDifferences(msg)
+		}
+		if m.Templates != nil {
+			return m.updateTemplates(msg)
+		}
+		if m.ViewMenu != nil {
+			return m.updateViewMenu(msg)
+		}
+
 		switch msg.String() {
-		case "ctrl+c", "q", "esc":
+		case "ctrl+c", "q":
 			return m, tea.Quit
+		case "esc":
+			if len(m.Crumbs) == 0 {
+				return m, tea.Quit
+			}
+			return m.back()
+		case "c":
+			return m, m.correlate()
+		case "d":
+			return m, m.compareAround()
+		case "D":
+			return m, m.compareCrumbs()
+		case "p":
+			return m, m.patterns()
+		case "t":
+			if m.Timeline != nil {
+				m.Timeline = nil
+				return m, nil
+			}
+			return m, m.timeline()
+		case "n":
+			return m.nextAnomaly()
+		case "left", "h":
+			return m.moveColumn(-1)
+		case "right", "l":
+			return m.moveColumn(1)
+		case "s":
+			if m.Summary != nil {
+				m.Summary = nil
+				return m, nil
+			}
+			return m, m.summarize("")
+		case "S":
+			return m.openGroupBy()
+		case "b":
+			return m, m.bookmark("", true)
+		case "B":
+			return m.openNote()
+		case "'":
+			return m, m.nextBookmark()
+		case "v":
+			if m.Views != nil {
+				m.ViewMenu = NewViewMenu(m.Views)
+			}
+		case "V":
+			return m.openSaveView()
+		case "/":
+			m.Prompt, m.prompting = NewPrompt("filter> ", m.Filter.String()), filterPrompt
+		case ":":
+			return m.openQuery("")
+		case "z":
+			m.Zone = nextZone(m.Layout.Zones(), m.Zone)
 		case "enter":
 			m.ShowFull = !m.ShowFull
 			if m.ShowFull && len(m.Lines) > 0 {
 				// Fetch JSON for selected line
-				id := m.Lines[m.SelectedRow][0].String()
-				return m, m.fetchFullRecord(id)
+				return m, m.fetchFullRecord(m.selectedID())
 			} else {
 				// Clear data when closing full record view
 				m.FullRecord = nil
 			}
 		case "up", "k":
-			if m.ShowFull {
-				// Navigate in full record view
-				if m.SelectedRow > 0 {
-					m.SelectedRow--
-					id := m.Lines[m.SelectedRow][0].String()
-					return m, m.fetchFullRecord(id)
-				} else if m.ScrollOffset > 0 {
-					m.ScrollOffset--
-					return m, tea.Sequence(m.loadData(), func() tea.Msg {
-						// After loading, fetch the new record
-						if len(m.Lines) > 0 {
-							id := m.Lines[m.SelectedRow][0].String()
-							return m.fetchFullRecord(id)()
-						}
-						return nil
-					})
-				}
-				return m, nil
-			}
-			if m.SelectedRow > 0 {
-				m.SelectedRow--
-			} else if m.ScrollOffset > 0 {
-				m.ScrollOffset--
-				return m, m.loadData()
-			}
+			return m.move(-1)
 		case "down", "j":
-			if m.ShowFull {
-				// Navigate in full record view
-				if m.SelectedRow < len(m.Lines)-1 {
-					m.SelectedRow++
-					id := m.Lines[m.SelectedRow][0].String()
-					return m, m.fetchFullRecord(id)
-				} else if m.ScrollOffset+len(m.Lines) < m.TotalLines {
-					m.ScrollOffset++
-					return m, tea.Sequence(m.loadData(), func() tea.Msg {
-						// After loading, fetch the new record
-						if len(m.Lines) > 0 {
-							id := m.Lines[m.SelectedRow][0].String()
-							return m.fetchFullRecord(id)()
-						}
-						return nil
-					})
-				}
+			return m.move(1)
+		case "pgup":
+			return m.move(-pageSize)
+		case "pgdown":
+			return m.move(pageSize)
+		case "+", "=":
+			return m.surround(m.Surround.Lines + 1)
+		case "-":
+			if !m.Surround.Active() {
 				return m, nil
 			}
-			if m.SelectedRow < len(m.Lines)-1 {
-				m.SelectedRow++
-			} else if m.ScrollOffset+len(m.Lines) < m.TotalLines {
-				m.ScrollOffset++
-				return m, m.loadData()
+			return m.surround(m.Surround.Lines - 1)
+		case "r":
+			// reverse time order
+			var sorts []Sort
+			if len(m.Sorts) == 0 {
+				sorts = []Sort{{Field: "timestamp", Desc: true}}
 			}
+			return m.setView(m.Filter, sorts)
+		case "home", "g":
+			return m, m.load("", topEdge)
+		case "end", "G":
+			return m, m.load("", bottomEdge)
 		}
 	case tea.WindowSizeMsg:
 		m.Width = msg.Width
@@ -212,25 +894,70 @@ func (m Model) View() tea.View {
 			encoder.SetIndent("", "  ")
 			encoder.SetEscapeHTML(false)
 
-			err := encoder.Encode(m.FullRecord)
+			// Stack traces read better verbatim than as an escaped string
+			record, stack := splitStack(m.FullRecord)
+
+			err := encoder.Encode(record)
 			if err != nil {
 				b.WriteString("Error pretty-printing JSON: " + err.Error())
 			} else {
 				// Encode adds a trailing newline, trim it
 				b.WriteString(strings.TrimSuffix(buf.String(), "\n"))
 			}
+			if stack != "" {
+				b.WriteString("\n\n" + stack)
+			}
 		} else {
 			b.WriteString("Loading full record...")
 		}
+	} else if m.Results != nil {
+		b.WriteString(m.Results.View(m.Width, m.Zone))
+	} else if m.Differences != nil {
+		b.WriteString(m.Differences.View(m.Width, m.Zone))
+	} else if m.Templates != nil {
+		b.WriteString(m.Templates.View(m.Width, m.Zone))
+	} else if m.ViewMenu != nil {
+		b.WriteString(m.ViewMenu.View(m.Width, m.Zone))
 	} else {
+		if len(m.Crumbs) > 0 {
+			b.WriteString(RenderCrumbs(m.Crumbs, m.Width))
+			b.WriteString("\n")
+		}
+		if m.Timeline != nil {
+			b.WriteString(RenderTimeline(*m.Timeline, m.selectedTime(), m.Width, m.Zone))
+			b.WriteString("\n")
+		}
 		// Render table
-		table := RenderTable(m.Fields, m.Lines, m.SelectedRow, m.Width, m.Layout)
+		table := RenderTable(m.Fields, m.Lines, m.SelectedRow, m.SelectedColumn, m.Width, m.layout(), m.Zone, m.Bookmarks)
 		b.WriteString(table)
+		if bm, ok := m.Bookmarks.Get(m.selectedID()); ok && bm.Note != "" {
+			b.WriteString("\n")
+			b.WriteString(RenderNote(bm, m.Width))
+		}
+		if m.Summary != nil {
+			b.WriteString("\n")
+			b.WriteString(RenderSummary(*m.Summary, m.Width))
+		}
+	}
+
+	if m.Prompt != nil {
+		b.WriteString("\n")
+		b.WriteString(m.Prompt.View(m.Width))
+	}
+
+	if m.Err != nil {
+		b.WriteString("\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Width(m.Width).Render(m.Err.Error()))
+	}
+
+	if m.Progress.Active() {
+		b.WriteString("\n")
+		b.WriteString(RenderProgress(m.Progress, m.Width))
 	}
 
 	// Render footer
 	b.WriteString("\n")
-	footer := RenderFooter(m.TotalLines, m.Width)
+	footer := RenderFooter(m.TotalLines, m.Width, m.Zone, m.Surround)
 	b.WriteString(footer)
 
 	v := tea.NewView(b.String())
@@ -278,3 +1005,29 @@ func parseJsonFields(data map[string]any, layout *Layout) map[string]any {
 
 	return data
 }
+
+// nextZone returns the zone after current, wrapping around
+func nextZone(zones []*time.Location, current *time.Location) *time.Location {
+	for i, zone := range zones {
+		if zone == current {
+			return zones[(i+1)%len(zones)]
+		}
+	}
+	return zones[0]
+}
+
+// splitStack separates an assembled stack trace from the rest of a record
+func splitStack(data map[string]any) (record map[string]any, stack string) {
+	stack, ok := data[stackKey].(string)
+	if !ok {
+		return data, ""
+	}
+
+	record = make(map[string]any, len(data))
+	for key, val := range data {
+		if key != stackKey {
+			record[key] = val
+		}
+	}
+	return record, stack
+}
@@ -0,0 +1,62 @@
+package parcours
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	stackKey      = "stack"
+	stackMaxLines = 1000
+)
+
+// Multiline specifies how continuation lines, such as stack traces, are
+// attached to the preceding record as its stack field.
+type Multiline struct {
+	// Pattern matches continuation lines
+	Pattern string `yaml:"pattern,omitempty"`
+	// Indent treats lines starting with whitespace as continuation
+	Indent bool `yaml:"indent,omitempty"`
+	// Unmatched treats lines the decoder cannot decode as continuation
+	Unmatched bool `yaml:"unmatched,omitempty"`
+	// MaxLines caps the lines attached to a single record
+	MaxLines int `yaml:"max_lines,omitempty"`
+}
+
+type multiline struct {
+	pattern   *regexp.Regexp
+	indent    bool
+	unmatched bool
+	maxLines  int
+}
+
+func (multi *Multiline) compile() (ml *multiline, err error) {
+
+	ml = &multiline{
+		indent:    multi.Indent,
+		unmatched: multi.Unmatched,
+		maxLines:  multi.MaxLines,
+	}
+	if ml.maxLines <= 0 {
+		ml.maxLines = stackMaxLines
+	}
+
+	if multi.Pattern != "" {
+		ml.pattern, err = regexp.Compile(multi.Pattern)
+		err = errors.Wrapf(err, "failed to compile multiline pattern")
+	}
+	return
+}
+
+func (ml *multiline) continues(line string) bool {
+
+	if ml.indent && strings.IndexAny(line, " \t") == 0 {
+		return true
+	}
+	if ml.pattern != nil && ml.pattern.MatchString(line) {
+		return true
+	}
+	return false
+}
@@ -21,23 +21,43 @@ type Field struct {
 // Store specifies a backing datastore.
 type Store interface {
 	// Load a file
-	Load(path string, last int) (err error)
+	Load(ctx context.Context, path string, opts LoadOptions) (err error)
 	// Follow a file
-	Follow(ctx context.Context, path string, last int) (err error)
+	Follow(ctx context.Context, path string, opts LoadOptions) (err error)
 	// Promote a field
-	Promote(field string) (err error)
+	Promote(ctx context.Context, field string) (err error)
 	//SetView Filter and Sort(s)
-	SetView(filter Filter, sorts []Sort) (err error)
+	SetView(ctx context.Context, filter Filter, sorts []Sort) (err error)
 	// GetView fields and count
-	GetView() (fields []Field, count int, err error)
+	GetView(ctx context.Context) (fields []Field, count int, err error)
 	// GetPage of log lines
-	GetPage(offset, size int) (lines []Line, err error)
+	GetPage(ctx context.Context, offset, size int) (lines []Line, err error)
+	// Locate the offset in the view of the line with id, or of its nearest
+	// neighbour in view order when it's filtered out
+	Locate(ctx context.Context, id string) (offset int, err error)
 	// GetJson returns raw json for a log line
-	GetJson(id string) (data map[string]any, err error)
+	GetJson(ctx context.Context, id string) (data map[string]any, err error)
 	// Tail streams log lines
 	Tail(ctx context.Context) (lines <-chan Line, err error)
 }
 
+// Cursor positions a page by a line of the view, for keyset pagination.
+type Cursor struct {
+	// ID of the line to page from, the start of the view, or end when Before, if empty
+	ID string
+	// Before pages back from ID, rather than forward
+	Before bool
+	// Inclusive includes the line with ID
+	Inclusive bool
+}
+
+// Seeker is implemented by stores that page by cursor, rather than offset,
+// keyed on sort fields and id, so that deep pages are as quick as the first.
+type Seeker interface {
+	// GetPageAt returns up to size lines after or before cursor, in view order
+	GetPageAt(ctx context.Context, cursor Cursor, size int) (lines []Line, err error)
+}
+
 type Config struct{}
 
 type Parcours struct {
@@ -0,0 +1,230 @@
+package parcours
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	timeKeys    = []string{"ts", "timestamp", "time", "@timestamp"}
+	levelKeys   = []string{"level"}
+	messageKeys = []string{"msg", "message"}
+)
+
+// Parser specifies a named pattern for decoding plain-text log lines.
+// Named captures become fields, with timestamp, level and message captures
+// mapped onto the corresponding record fields.
+// A parser without regex or grok decodes ndjson, still honoring its time settings.
+type Parser struct {
+	Name     string            `yaml:"name"`
+	Regex    string            `yaml:"regex,omitempty"`
+	Grok     string            `yaml:"grok,omitempty"`
+	Patterns map[string]string `yaml:"patterns,omitempty"`
+	// TimeField overrides the usual timestamp fields, ts, time and the like
+	TimeField string `yaml:"time_field,omitempty"`
+	// TimeFormat is a Go layout or one of epoch_s, epoch_ms, epoch_us, epoch_ns,
+	// detected when empty
+	TimeFormat string `yaml:"time_format,omitempty"`
+}
+
+// Decoder decodes lines from a log source into records.
+// Lines are ndjson unless decoding with a parser.
+// Decoders are stateful when assembling multi-line records.
+type Decoder struct {
+	pattern    *regexp.Regexp
+	timeKeys   []string
+	timeFormat string
+	multi      *multiline
+	pending    *Record
+	stack      []string
+}
+
+// NewDecoder creates a decoder for parser, or for ndjson when parser is nil.
+// Records span multiple lines when multi is not nil.
+func NewDecoder(parser *Parser, multi *Multiline) (dec *Decoder, err error) {
+
+	dec = &Decoder{timeKeys: timeKeys}
+	if multi != nil {
+		dec.multi, err = multi.compile()
+		if err != nil {
+			return
+		}
+	}
+	if parser == nil {
+		return
+	}
+
+	dec.timeFormat = parser.TimeFormat
+	if parser.TimeField != "" {
+		dec.timeKeys = []string{parser.TimeField}
+	}
+
+	expr := parser.Regex
+	if parser.Grok != "" {
+		expr, err = expandGrok(parser.Grok, parser.Patterns, 0)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to expand grok for parser %s", parser.Name)
+			return
+		}
+	}
+	if expr == "" {
+		return
+	}
+
+	dec.pattern, err = regexp.Compile(expr)
+	err = errors.Wrapf(err, "failed to compile pattern for parser %s", parser.Name)
+	return
+}
+
+// Decode a line, returning any records it completes.
+// Lines that cannot be decoded are kept whole as the message.
+func (dec *Decoder) Decode(line string) (recs []Record) {
+
+	if dec.multi == nil {
+		rec, _ := dec.decodeLine(line)
+		recs = []Record{rec}
+		return
+	}
+
+	if dec.pending != nil && dec.multi.continues(line) {
+		recs = dec.push(line)
+		return
+	}
+
+	rec, ok := dec.decodeLine(line)
+	if !ok && dec.pending != nil && dec.multi.unmatched {
+		recs = dec.push(line)
+		return
+	}
+
+	recs = dec.Flush()
+	dec.pending = &rec
+	return
+}
+
+// Flush returns the pending multi-line record, if any.
+func (dec *Decoder) Flush() (recs []Record) {
+
+	if dec.pending == nil {
+		return
+	}
+
+	rec := *dec.pending
+	if len(dec.stack) > 0 {
+		rec.Data[stackKey] = strings.Join(dec.stack, "\n")
+	}
+
+	dec.pending = nil
+	dec.stack = nil
+	recs = []Record{rec}
+	return
+}
+
+// UnmarshalRaw decodes a record's raw JSON, as stores keep it, with numbers
+// as json.Number so that large ones, such as ids, stay intact.
+func UnmarshalRaw(raw []byte) (data map[string]any, err error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&data)
+	err = errors.Wrapf(err, "failed to unmarshal raw JSON")
+	return
+}
+
+// unexported
+
+// clone of dec, as configured but without any record pending
+func (dec *Decoder) clone() *Decoder {
+
+	cloned := *dec
+	cloned.pending = nil
+	cloned.stack = nil
+	return &cloned
+}
+
+// push a continuation line onto the stack, flushing the pending record once
+// the stack is full
+func (dec *Decoder) push(line string) (recs []Record) {
+
+	dec.stack = append(dec.stack, line)
+	if len(dec.stack) < dec.multi.maxLines {
+		return
+	}
+	recs = dec.Flush()
+	return
+}
+
+func (dec *Decoder) decodeLine(line string) (rec Record, ok bool) {
+
+	if dec.pattern == nil {
+		rec.Data, ok = decodeJson(line)
+	} else {
+		rec.Data, ok = dec.decodeText(line)
+	}
+	if !ok {
+		rec.Message = line
+		rec.Data = map[string]any{"msg": line}
+		return
+	}
+
+	// lines without a usable timestamp are left without one
+	if val, ok := lookup(rec.Data, dec.timeKeys); ok {
+		rec.Timestamp = ParseTime(val, dec.timeFormat)
+	}
+	// original level is kept in data
+	if val, ok := lookup(rec.Data, levelKeys); ok {
+		rec.Level = NormalizeLevel(val).String()
+	}
+	if val, ok := lookup(rec.Data, messageKeys); ok {
+		rec.Message = fmt.Sprintf("%v", val)
+	}
+
+	return
+}
+
+func decodeJson(line string) (data map[string]any, ok bool) {
+
+	// UseNumber keeps large integers, such as epoch nanos, intact
+	decoder := json.NewDecoder(bytes.NewBufferString(line))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&data)
+	ok = err == nil && data != nil
+	return
+}
+
+func (dec *Decoder) decodeText(line string) (data map[string]any, ok bool) {
+
+	match := dec.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return
+	}
+
+	data = map[string]any{}
+	for i, name := range dec.pattern.SubexpNames() {
+		if name == "" || match[i] == "" {
+			continue
+		}
+		data[name] = match[i]
+	}
+
+	ok = true
+	return
+}
+
+func lookup(data map[string]any, keys []string) (val any, ok bool) {
+
+	for _, key := range keys {
+		val, ok = data[key]
+		if ok {
+			return
+		}
+	}
+	return
+}
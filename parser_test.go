@@ -0,0 +1,196 @@
+package parcours
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecodeGrok(t *testing.T) {
+
+	dec, err := NewDecoder(&Parser{
+		Name:     "app",
+		Grok:     `%{TIMESTAMP_ISO8601:timestamp} %{LOGLEVEL:level} \[%{REQ:req.id}\] %{GREEDYDATA:message}`,
+		Patterns: map[string]string{"REQ": `r-%{POSINT}`},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %+v", err)
+	}
+
+	recs := dec.Decode("2025-11-13T20:30:00.250Z WARNING [r-42] disk almost full")
+	if len(recs) != 1 {
+		t.Fatalf("expected a record, got %d", len(recs))
+	}
+	rec := recs[0]
+	if !rec.Timestamp.Equal(time.Date(2025, 11, 13, 20, 30, 0, 250e6, time.UTC)) {
+		t.Errorf("expected timestamp parsed, got %v", rec.Timestamp)
+	}
+	if rec.Level != "warn" || rec.Message != "disk almost full" {
+		t.Errorf("expected warn and message, got %q and %q", rec.Level, rec.Message)
+	}
+	// dotted names captured with underscores, and levels kept as logged
+	if rec.Data["req_id"] != "r-42" || rec.Data["level"] != "WARNING" {
+		t.Errorf("expected captures in data, got %v", rec.Data)
+	}
+
+	recs = dec.Decode("something else entirely")
+	if len(recs) != 1 || recs[0].Message != "something else entirely" || !recs[0].Timestamp.IsZero() {
+		t.Errorf("expected unmatched line kept whole, got %+v", recs)
+	}
+
+	for _, parser := range []Parser{
+		{Name: "unknown", Grok: "%{NOPE:x}"},
+		{Name: "recursive", Grok: "%{A}", Patterns: map[string]string{"A": "%{B}", "B": "%{A}"}},
+		{Name: "invalid", Regex: "(unclosed"},
+	} {
+		_, err = NewDecoder(&parser, nil)
+		if err == nil {
+			t.Errorf("expected error creating decoder for parser %s", parser.Name)
+		}
+	}
+}
+
+func TestDecodeUntimed(t *testing.T) {
+
+	dec, err := NewDecoder(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %+v", err)
+	}
+
+	lines := []string{
+		`{"ts":"2025-11-13T20:00:00Z","msg":"timed"}`,
+		`{"msg":"no timestamp"}`,
+		`{"ts":"whenever","msg":"unusable timestamp"}`,
+		"not json",
+	}
+	expected := []time.Time{time.Date(2025, 11, 13, 20, 0, 0, 0, time.UTC), {}, {}, {}}
+	for i, line := range lines {
+		recs := dec.Decode(line)
+		if len(recs) != 1 {
+			t.Fatalf("expected a record of %q, got %d", line, len(recs))
+		}
+		if !recs[0].Timestamp.Equal(expected[i]) {
+			t.Errorf("expected %q timestamped %v, got %v", line, expected[i], recs[0].Timestamp)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+
+	at := time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		val      any
+		format   string
+		expected time.Time
+	}{
+		{name: "seconds", val: json.Number("1763065800"), expected: at},
+		{name: "millis", val: json.Number("1763065800123"), expected: at.Add(123 * time.Millisecond)},
+		{name: "micros", val: json.Number("1763065800123456"), expected: at.Add(123456 * time.Microsecond)},
+		{name: "nanos", val: json.Number("1763065800123456789"), expected: at.Add(123456789)},
+		{name: "fractional seconds", val: json.Number("1763065800.5"), expected: at.Add(500 * time.Millisecond)},
+		{name: "float seconds", val: 1763065800.25, expected: at.Add(250 * time.Millisecond)},
+		{name: "int millis", val: int64(1763065800123), expected: at.Add(123 * time.Millisecond)},
+		{name: "epoch string", val: "1763065800", expected: at},
+		{name: "format overrides magnitude", val: json.Number("1500"), format: "epoch_ms", expected: time.Unix(1, 500e6).UTC()},
+		{name: "zero epoch", val: json.Number("0")},
+		{name: "negative epoch", val: int64(-5)},
+		{name: "rfc3339", val: "2025-11-13T21:30:00+01:00", expected: at},
+		{name: "space separated", val: "2025-11-13 20:30:00,5", expected: at.Add(500 * time.Millisecond)},
+		{name: "http date", val: "13/Nov/2025:20:30:00 +0000", expected: at},
+		{name: "layout", val: "13.11.2025 20:30", format: "02.01.2006 15:04", expected: at},
+		{name: "layout not matching", val: "2025-11-13T20:30:00Z", format: "02.01.2006 15:04"},
+		{name: "garbage", val: "yesterday"},
+		{name: "unsupported type", val: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := ParseTime(tc.val, tc.format)
+			if !ts.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, ts)
+			}
+		})
+	}
+}
+
+func TestDecodeMultiline(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		multi    Multiline
+		lines    []string
+		messages []string
+		stacks   []string
+	}{
+		{
+			name:  "pattern",
+			multi: Multiline{Pattern: `^(goroutine |main\.)`},
+			lines: []string{
+				`{"msg":"panic"}`, "goroutine 1 [running]:", "main.main()",
+				`{"msg":"after"}`,
+			},
+			messages: []string{"panic", "after"},
+			stacks:   []string{"goroutine 1 [running]:\nmain.main()", ""},
+		},
+		{
+			name:     "indent",
+			multi:    Multiline{Indent: true},
+			lines:    []string{`{"msg":"failed"}`, "\tat a", "  at b", `{"msg":"next"}`, " at c"},
+			messages: []string{"failed", "next"},
+			stacks:   []string{"\tat a\n  at b", " at c"},
+		},
+		{
+			name:     "unmatched",
+			multi:    Multiline{Unmatched: true},
+			lines:    []string{"orphan", "still orphan", `{"msg":"json"}`, "trailer"},
+			messages: []string{"orphan", "json"},
+			stacks:   []string{"still orphan", "trailer"},
+		},
+		{
+			name:     "max lines",
+			multi:    Multiline{Indent: true, MaxLines: 2},
+			lines:    []string{`{"msg":"long"}`, " 1", " 2", " 3"},
+			messages: []string{"long", " 3"},
+			stacks:   []string{" 1\n 2", ""},
+		},
+		{
+			name:     "max unmatched lines",
+			multi:    Multiline{Unmatched: true, MaxLines: 2},
+			lines:    []string{`{"msg":"long"}`, "1", "2", "3", "4"},
+			messages: []string{"long", "3"},
+			stacks:   []string{"1\n2", "4"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dec, err := NewDecoder(nil, &tc.multi)
+			if err != nil {
+				t.Fatalf("failed to create decoder: %+v", err)
+			}
+
+			var recs []Record
+			for _, line := range tc.lines {
+				recs = append(recs, dec.Decode(line)...)
+			}
+			recs = append(recs, dec.Flush()...)
+
+			var messages, stacks []string
+			for _, rec := range recs {
+				stack, _ := rec.Data[stackKey].(string)
+				messages, stacks = append(messages, rec.Message), append(stacks, stack)
+			}
+			if !reflect.DeepEqual(messages, tc.messages) || !reflect.DeepEqual(stacks, tc.stacks) {
+				t.Errorf("expected messages %q with stacks %q, got %q with %q", tc.messages, tc.stacks, messages, stacks)
+			}
+		})
+	}
+
+	_, err := NewDecoder(nil, &Multiline{Pattern: "(unclosed"})
+	if err == nil {
+		t.Errorf("expected error creating decoder with an invalid multiline pattern")
+	}
+}
@@ -0,0 +1,234 @@
+package parcours
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// PatternField filters lines by the id of their message's pattern.
+const PatternField = "pattern"
+
+const (
+	// wildcard stands in for the variable parts of a template
+	wildcard = "<*>"
+	// prefixDepth is how many leading tokens group messages for comparison
+	prefixDepth = 2
+	// minSimilarity is the share of tokens a message must have in common
+	// with a template to be mined into it
+	minSimilarity = 0.5
+)
+
+// Pattern of messages, with the lines in view having it.
+type Pattern struct {
+	ID       int64  `json:"id"`
+	Template string `json:"template"`
+	Count    int    `json:"count"`
+}
+
+// Patterner is implemented by stores that mine patterns from messages.
+type Patterner interface {
+	// Patterns of lines in view, most frequent first
+	Patterns(ctx context.Context) (patterns []Pattern, err error)
+}
+
+// Miner mines templates from messages, Drain style: a message is compared
+// with the templates of messages having as many tokens and the same leading
+// ones, joining the most similar if enough tokens match, those that don't
+// becoming wildcards, or else starting a template of its own.
+// Ids are given in order of first appearance, so mining the same messages
+// in the same order gives the same ids.
+type Miner struct {
+	mu        sync.Mutex
+	groups    map[string][]*template
+	templates []*template
+}
+
+type template struct {
+	id     int64
+	tokens []string
+}
+
+// NewMiner creates a Miner with no templates.
+func NewMiner() *Miner {
+	return &Miner{groups: map[string][]*template{}}
+}
+
+// Add a message, returning the id of its pattern.
+func (mnr *Miner) Add(message string) (id int64) {
+
+	tokens := tokenize(message)
+	key := groupKey(tokens)
+
+	mnr.mu.Lock()
+	defer mnr.mu.Unlock()
+
+	var best *template
+	var bestSim float64
+	for _, tmpl := range mnr.groups[key] {
+		sim := tmpl.similarity(tokens)
+		if sim >= minSimilarity && sim > bestSim {
+			best, bestSim = tmpl, sim
+		}
+	}
+
+	if best == nil {
+		best = &template{id: int64(len(mnr.templates)) + 1, tokens: tokens}
+		mnr.templates = append(mnr.templates, best)
+		mnr.groups[key] = append(mnr.groups[key], best)
+		return best.id
+	}
+
+	for i, token := range tokens {
+		if best.tokens[i] != token {
+			best.tokens[i] = wildcard
+		}
+	}
+	return best.id
+}
+
+// Template of the pattern with id, empty when there's none.
+func (mnr *Miner) Template(id int64) string {
+
+	mnr.mu.Lock()
+	defer mnr.mu.Unlock()
+
+	if id < 1 || id > int64(len(mnr.templates)) {
+		return ""
+	}
+	return strings.Join(mnr.templates[id-1].tokens, " ")
+}
+
+// Templates of the patterns in view, shown in place of it until closed.
+type Templates struct {
+	Patterns []Pattern
+	// Results lists the patterns, a line for each
+	Results *Results
+}
+
+// NewTemplates lists patterns.
+func NewTemplates(patterns []Pattern) *Templates {
+
+	fields := []Field{
+		{Name: "count", Type: "BIGINT"},
+		{Name: "template", Type: "VARCHAR"},
+	}
+
+	lines := make([]Line, len(patterns))
+	for i, pattern := range patterns {
+		lines[i] = Line{{Raw: int64(pattern.Count)}, {Raw: pattern.Template}}
+	}
+
+	return &Templates{
+		Patterns: patterns,
+		Results:  NewResults("", fields, lines),
+	}
+}
+
+// Selected pattern, if any.
+func (tmpls *Templates) Selected() (pattern Pattern, ok bool) {
+
+	at := tmpls.Results.ScrollOffset + tmpls.Results.SelectedRow
+	if at >= len(tmpls.Patterns) {
+		return
+	}
+	return tmpls.Patterns[at], true
+}
+
+// View renders a page of patterns.
+func (tmpls *Templates) View(width int, zone *time.Location) string {
+
+	header := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).
+		Render(fmt.Sprintf("%d patterns | enter filter | esc close", len(tmpls.Patterns)))
+	res := tmpls.Results
+	return header + "\n" + RenderTable(res.Fields, res.Page(), res.SelectedRow, -1, width, res.Layout, zone, nil)
+}
+
+// unexported
+
+// similarity of tokens to the template, as the share of them in common,
+// wildcards matching anything
+func (tmpl *template) similarity(tokens []string) float64 {
+
+	if len(tokens) == 0 {
+		return 1
+	}
+
+	same := 0
+	for i, token := range tokens {
+		if tmpl.tokens[i] == token || tmpl.tokens[i] == wildcard {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens))
+}
+
+// tokenize a message by whitespace, tokens with digits being taken as
+// variable from the outset
+func tokenize(message string) (tokens []string) {
+
+	tokens = strings.Fields(message)
+	for i, token := range tokens {
+		if strings.ContainsFunc(token, unicode.IsDigit) {
+			tokens[i] = wildcard
+		}
+	}
+	return
+}
+
+// groupKey of tokens, their count and leading ones
+func groupKey(tokens []string) string {
+
+	key := []string{strconv.Itoa(len(tokens))}
+	key = append(key, tokens[:min(len(tokens), prefixDepth)]...)
+	return strings.Join(key, " ")
+}
+
+type patternsMsg struct {
+	patterns []Pattern
+	err      error
+}
+
+// patterns in view, when the store mines them
+func (m Model) patterns() tea.Cmd {
+	patterner, ok := m.Store.(Patterner)
+	if !ok {
+		return nil
+	}
+	return func() tea.Msg {
+		patterns, err := patterner.Patterns(m.loads.ctx)
+		return patternsMsg{patterns: patterns, err: err}
+	}
+}
+
+// updateTemplates navigates patterns until they're closed, pivoting to the
+// lines having the selected one
+func (m Model) updateTemplates(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.Templates = nil
+	case "enter":
+		pattern, ok := m.Templates.Selected()
+		if !ok {
+			return m, nil
+		}
+		m.Templates = nil
+		filter := m.Filter
+		match := Filter{Op: Eq, Field: PatternField, Value: pattern.ID}
+		return m.pivot(pattern.Template, Filter{Op: And, Children: []*Filter{&filter, &match}}, m.Sorts)
+	case "z":
+		m.Zone = nextZone(m.Layout.Zones(), m.Zone)
+	default:
+		m.Templates.Results.Navigate(msg.String())
+	}
+	return m, nil
+}
@@ -0,0 +1,60 @@
+package parcours
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMiner(t *testing.T) {
+
+	messages := []struct {
+		message  string
+		id       int64
+		template string
+	}{
+		{message: "request GET /api/users took 5ms", id: 1, template: "request GET /api/users took <*>"},
+		{message: "request GET /api/orders took 7ms", id: 1, template: "request GET <*> took <*>"},
+		// as many tokens, leading with others
+		{message: "request POST /api/orders took 9ms", id: 2, template: "request POST /api/orders took <*>"},
+		// leading the same, with fewer tokens
+		{message: "request GET failed", id: 3, template: "request GET failed"},
+		{message: "cache miss for key users", id: 4, template: "cache miss for key users"},
+		// wildcards matching anything
+		{message: "request GET never got answered", id: 1, template: "request GET <*> <*> <*>"},
+		// too few tokens in common
+		{message: "cache miss quite badly today", id: 5, template: "cache miss quite badly today"},
+		{message: "worker 12 started", id: 6, template: "worker <*> started"},
+		{message: "worker 7  started", id: 6, template: "worker <*> started"},
+		{message: "", id: 7, template: ""},
+		{message: "  ", id: 7, template: ""},
+	}
+
+	mnr := NewMiner()
+	var ids []int64
+	for _, tc := range messages {
+		id := mnr.Add(tc.message)
+		if id != tc.id {
+			t.Errorf("expected %q mined into %d, got %d", tc.message, tc.id, id)
+		}
+		if tmpl := mnr.Template(id); tmpl != tc.template {
+			t.Errorf("expected template %q after %q, got %q", tc.template, tc.message, tmpl)
+		}
+		ids = append(ids, id)
+	}
+
+	for _, id := range []int64{0, -1, 8} {
+		if tmpl := mnr.Template(id); tmpl != "" {
+			t.Errorf("expected no template with id %d, got %q", id, tmpl)
+		}
+	}
+
+	// mining the same messages in the same order gives the same ids
+	again := NewMiner()
+	var againIDs []int64
+	for _, tc := range messages {
+		againIDs = append(againIDs, again.Add(tc.message))
+	}
+	if !reflect.DeepEqual(againIDs, ids) {
+		t.Errorf("expected ids %v mining again, got %v", ids, againIDs)
+	}
+}
@@ -0,0 +1,83 @@
+package parcours
+
+import (
+	"sync"
+)
+
+// Progress of a long running store operation, such as a load or promotion.
+type Progress struct {
+	Phase      string // what's underway, empty when idle
+	Bytes      int64  // read so far
+	TotalBytes int64  // to be read, zero when unknown
+	Rows       int64  // ingested or backfilled so far
+	TotalRows  int64  // to be backfilled, zero when unknown
+}
+
+// Active reports whether an operation is underway.
+func (pg Progress) Active() bool {
+	return pg.Phase != ""
+}
+
+// Fraction done, by bytes or else rows, zero when unknown.
+func (pg Progress) Fraction() float64 {
+
+	switch {
+	case pg.TotalBytes > 0:
+		return min(float64(pg.Bytes)/float64(pg.TotalBytes), 1)
+	case pg.TotalRows > 0:
+		return min(float64(pg.Rows)/float64(pg.TotalRows), 1)
+	}
+	return 0
+}
+
+// Reporter is implemented by stores that report progress.
+type Reporter interface {
+	// Progress of the current operation
+	Progress() Progress
+}
+
+// Tracker keeps progress for stores implementing Reporter.
+type Tracker struct {
+	mu       sync.Mutex
+	progress Progress
+}
+
+// Start a phase, with totals if known.
+func (tr *Tracker) Start(phase string, totalBytes, totalRows int64) {
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.progress = Progress{Phase: phase, TotalBytes: totalBytes, TotalRows: totalRows}
+}
+
+// Read sets bytes read.
+func (tr *Tracker) Read(bytes int64) {
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.progress.Bytes = bytes
+}
+
+// Ingest adds to rows.
+func (tr *Tracker) Ingest(rows int) {
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.progress.Rows += int64(rows)
+}
+
+// Finish the current phase, becoming idle.
+func (tr *Tracker) Finish() {
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	tr.progress = Progress{}
+}
+
+// Progress returns the current progress.
+func (tr *Tracker) Progress() Progress {
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	return tr.progress
+}
@@ -0,0 +1,74 @@
+package parcours
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+
+	var tr Tracker
+	if pg := tr.Progress(); pg.Active() || pg.Fraction() != 0 {
+		t.Errorf("expected idle tracker, got %+v", pg)
+	}
+
+	// by bytes when their total's known
+	tr.Start("loading", 200, 0)
+	tr.Read(50)
+	tr.Ingest(3)
+	tr.Ingest(4)
+	pg := tr.Progress()
+	if !pg.Active() || pg.Phase != "loading" || pg.Bytes != 50 || pg.Rows != 7 {
+		t.Errorf("expected loading 50 bytes and 7 rows, got %+v", pg)
+	}
+	if pg.Fraction() != 0.25 {
+		t.Errorf("expected a quarter done, got %v", pg.Fraction())
+	}
+	tr.Read(300)
+	if fraction := tr.Progress().Fraction(); fraction != 1 {
+		t.Errorf("expected done at most, got %v", fraction)
+	}
+
+	// starting over, by rows
+	tr.Start("promoting", 0, 40)
+	tr.Ingest(10)
+	pg = tr.Progress()
+	if pg.Phase != "promoting" || pg.Bytes != 0 || pg.Rows != 10 || pg.Fraction() != 0.25 {
+		t.Errorf("expected a quarter promoted, got %+v", pg)
+	}
+
+	// neither total known
+	tr.Start("following", 0, 0)
+	tr.Read(10)
+	if fraction := tr.Progress().Fraction(); fraction != 0 {
+		t.Errorf("expected unknown fraction, got %v", fraction)
+	}
+
+	tr.Finish()
+	if pg := tr.Progress(); pg != (Progress{}) {
+		t.Errorf("expected idle once finished, got %+v", pg)
+	}
+}
+
+func TestTrackerConcurrent(t *testing.T) {
+
+	var tr Tracker
+	tr.Start("loading", 0, 0)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				tr.Ingest(1)
+				_ = tr.Progress()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if rows := tr.Progress().Rows; rows != 800 {
+		t.Errorf("expected 800 rows ingested, got %d", rows)
+	}
+}
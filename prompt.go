@@ -0,0 +1,157 @@
+package parcours
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// Prompt edits a line of input in the TUI, such as a query.
+type Prompt struct {
+	Label string
+	// Err from the last submission, shown until the next edit
+	Err error
+
+	value  []rune
+	cursor int
+}
+
+// NewPrompt labelled label, starting with value.
+func NewPrompt(label, value string) *Prompt {
+
+	pr := &Prompt{Label: label}
+	pr.value = []rune(value)
+	pr.cursor = len(pr.value)
+	return pr
+}
+
+// Value being edited.
+func (pr *Prompt) Value() string {
+	return string(pr.value)
+}
+
+// Insert text at the cursor, as typed or pasted, newlines becoming spaces.
+func (pr *Prompt) Insert(text string) {
+
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, text)
+
+	runes := []rune(text)
+	pr.value = slices.Insert(pr.value, pr.cursor, runes...)
+	pr.cursor += len(runes)
+	pr.Err = nil
+}
+
+// MoveTo moves the cursor to rune at, within the value.
+func (pr *Prompt) MoveTo(at int) {
+	pr.cursor = max(min(at, len(pr.value)), 0)
+}
+
+// Update edits per key, reporting whether the line was submitted or cancelled.
+func (pr *Prompt) Update(msg tea.KeyPressMsg) (submit, cancel bool) {
+
+	switch msg.String() {
+	case "enter":
+		return true, false
+	case "esc", "ctrl+c":
+		return false, true
+	case "left", "ctrl+b":
+		pr.cursor = max(pr.cursor-1, 0)
+	case "right", "ctrl+f":
+		pr.cursor = min(pr.cursor+1, len(pr.value))
+	case "home", "ctrl+a":
+		pr.cursor = 0
+	case "end", "ctrl+e":
+		pr.cursor = len(pr.value)
+	case "alt+left", "alt+b":
+		pr.cursor = pr.wordStart()
+	case "alt+right", "alt+f":
+		pr.cursor = pr.wordEnd()
+	case "backspace", "ctrl+h":
+		pr.cut(pr.cursor-1, pr.cursor)
+	case "delete", "ctrl+d":
+		pr.cut(pr.cursor, pr.cursor+1)
+	case "ctrl+w", "alt+backspace":
+		pr.cut(pr.wordStart(), pr.cursor)
+	case "ctrl+u":
+		pr.cut(0, pr.cursor)
+	case "ctrl+k":
+		pr.cut(pr.cursor, len(pr.value))
+	default:
+		if msg.Text != "" {
+			pr.Insert(msg.Text)
+		}
+	}
+	return false, false
+}
+
+// View renders the prompt with its cursor, and any error beneath.
+func (pr *Prompt) View(width int) string {
+
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+	under := " "
+	if pr.cursor < len(pr.value) {
+		under = string(pr.value[pr.cursor])
+	}
+
+	var b strings.Builder
+	b.WriteString(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render(pr.Label))
+	b.WriteString(string(pr.value[:pr.cursor]))
+	b.WriteString(cursorStyle.Render(under))
+	if pr.cursor < len(pr.value) {
+		b.WriteString(string(pr.value[pr.cursor+1:]))
+	}
+
+	if pr.Err != nil {
+		b.WriteString("\n")
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Width(width).Render(pr.Err.Error()))
+	}
+	return b.String()
+}
+
+// unexported
+
+// cut runes from start to end, within the value
+func (pr *Prompt) cut(start, end int) {
+
+	start, end = max(start, 0), min(end, len(pr.value))
+	if start >= end {
+		return
+	}
+	pr.value = slices.Delete(pr.value, start, end)
+	pr.cursor = start
+	pr.Err = nil
+}
+
+// wordStart is the start of the word before the cursor
+func (pr *Prompt) wordStart() int {
+
+	at := pr.cursor
+	for at > 0 && unicode.IsSpace(pr.value[at-1]) {
+		at--
+	}
+	for at > 0 && !unicode.IsSpace(pr.value[at-1]) {
+		at--
+	}
+	return at
+}
+
+// wordEnd is the end of the word after the cursor
+func (pr *Prompt) wordEnd() int {
+
+	at := pr.cursor
+	for at < len(pr.value) && unicode.IsSpace(pr.value[at]) {
+		at++
+	}
+	for at < len(pr.value) && !unicode.IsSpace(pr.value[at]) {
+		at++
+	}
+	return at
+}
@@ -0,0 +1,158 @@
+package parcours
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// Querier is implemented by stores that run ad-hoc, read-only SQL against
+// their tables.
+type Querier interface {
+	// Query runs a statement, returning its columns as fields and rows as lines
+	Query(ctx context.Context, query string) (fields []Field, lines []Line, err error)
+}
+
+// Results of a query, shown in place of the view.
+type Results struct {
+	Query  string
+	Fields []Field
+	Lines  []Line
+	Layout *Layout
+
+	// Display state
+	ScrollOffset int
+	SelectedRow  int
+}
+
+// NewResults of query, laid out to fit their values.
+func NewResults(query string, fields []Field, lines []Line) *Results {
+	return &Results{
+		Query:  query,
+		Fields: fields,
+		Lines:  lines,
+		Layout: AutoLayout(fields, lines),
+	}
+}
+
+// Page of results at the scroll offset.
+func (res *Results) Page() []Line {
+	return res.Lines[res.ScrollOffset:min(res.ScrollOffset+pageSize, len(res.Lines))]
+}
+
+// Move the selection by delta lines, scrolling as needed.
+func (res *Results) Move(delta int) {
+
+	at := max(min(res.ScrollOffset+res.SelectedRow+delta, len(res.Lines)-1), 0)
+	switch {
+	case at < res.ScrollOffset:
+		res.ScrollOffset = at
+	case at >= res.ScrollOffset+pageSize:
+		res.ScrollOffset = at - pageSize + 1
+	}
+	res.SelectedRow = at - res.ScrollOffset
+}
+
+// Navigate by a key moving the selection, if it's one of them.
+func (res *Results) Navigate(key string) {
+
+	switch key {
+	case "up", "k":
+		res.Move(-1)
+	case "down", "j":
+		res.Move(1)
+	case "pgup":
+		res.Move(-pageSize)
+	case "pgdown":
+		res.Move(pageSize)
+	case "home", "g":
+		res.Move(-len(res.Lines))
+	case "end", "G":
+		res.Move(len(res.Lines))
+	}
+}
+
+// View renders the query and a page of results.
+func (res *Results) View(width int, zone *time.Location) string {
+
+	header := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).
+		Render(fmt.Sprintf("%s | %d rows | : edit | esc close", res.Query, len(res.Lines)))
+	return header + "\n" + RenderTable(res.Fields, res.Page(), res.SelectedRow, -1, width, res.Layout, zone, nil)
+}
+
+// unexported
+
+type queryMsg struct {
+	query  string
+	fields []Field
+	lines  []Line
+	err    error
+}
+
+// openQuery opens a prompt for sql, when the store runs queries
+func (m Model) openQuery(query string) (Model, tea.Cmd) {
+	if _, ok := m.Store.(Querier); !ok {
+		return m, nil
+	}
+	m.Prompt, m.prompting = NewPrompt("sql> ", query), queryPrompt
+	return m, nil
+}
+
+// runQuery runs sql, the prompt staying open with any error
+func (m Model) runQuery(query string) tea.Cmd {
+	querier := m.Store.(Querier)
+	return func() tea.Msg {
+		fields, lines, err := querier.Query(m.loads.ctx, query)
+		return queryMsg{query: query, fields: fields, lines: lines, err: err}
+	}
+}
+
+// updatePrompt edits the open prompt, acting on what's submitted
+func (m Model) updatePrompt(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	submit, cancel := m.Prompt.Update(msg)
+	switch {
+	case cancel:
+		m.Prompt, m.prompting, m.filtering = nil, 0, false
+	case submit && m.prompting == queryPrompt:
+		if strings.TrimSpace(m.Prompt.Value()) != "" {
+			return m, m.runQuery(m.Prompt.Value())
+		}
+	case submit && m.prompting == statsPrompt:
+		by := strings.TrimSpace(m.Prompt.Value())
+		m.Prompt, m.prompting = nil, 0
+		return m, m.summarize(by)
+	case submit && m.prompting == notePrompt:
+		note := strings.TrimSpace(m.Prompt.Value())
+		m.Prompt, m.prompting = nil, 0
+		return m, m.bookmark(note, false)
+	case submit && m.prompting == filterPrompt:
+		return m.submitFilter(m.Prompt.Value())
+	case submit && m.prompting == viewPrompt:
+		if name := strings.TrimSpace(m.Prompt.Value()); name != "" {
+			m.Prompt, m.prompting = nil, 0
+			return m.saveView(name), nil
+		}
+	}
+	return m, nil
+}
+
+// updateResults navigates query results until they're closed
+func (m Model) updateResults(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.Results = nil
+	case ":":
+		return m.openQuery(m.Results.Query)
+	case "z":
+		m.Zone = nextZone(m.Layout.Zones(), m.Zone)
+	default:
+		m.Results.Navigate(msg.String())
+	}
+	return m, nil
+}
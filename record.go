@@ -0,0 +1,15 @@
+package parcours
+
+import (
+	"time"
+)
+
+// Record is a single log entry decoded from a source, ready for a store.
+type Record struct {
+	Timestamp time.Time
+	// Level is canonical, see Levels
+	Level   string
+	Message string
+	// Data holds every field of the entry, including those mapped above.
+	Data map[string]any
+}
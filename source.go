@@ -0,0 +1,333 @@
+package parcours
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	batchSize    = 10000
+	pollInterval = 250 * time.Millisecond
+)
+
+// Source reads records from a log file for a store.
+type Source struct {
+	path    string
+	decoder *Decoder
+	file    *os.File
+	rdr     *bufio.Reader
+	partial string
+	read    int64
+	size    int64
+	tracker *Tracker
+}
+
+// OpenSource opens a log file, decoding its lines as dec does, with state
+// of its own so that sources can be read side by side.
+func OpenSource(path string, dec *Decoder) (src *Source, err error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to open log file")
+		return
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		err = errors.Wrapf(err, "failed to stat log file")
+		return
+	}
+
+	src = &Source{
+		path:    path,
+		decoder: dec.clone(),
+		file:    file,
+		rdr:     bufio.NewReader(file),
+		size:    info.Size(),
+	}
+	return
+}
+
+// Size of the file when opened.
+func (src *Source) Size() int64 {
+	return src.size
+}
+
+// Track bytes read and records inserted with tracker.
+func (src *Source) Track(tracker *Tracker) {
+	src.tracker = tracker
+}
+
+// Close the underlying file.
+func (src *Source) Close() error {
+	return src.file.Close()
+}
+
+// Insert hands a batch of records read from the source at path to a store.
+type Insert func(ctx context.Context, path string, recs []Record) (err error)
+
+// Load reads records to the end of the source, handing those within opts
+// to insert in batches, and keeping only the last when opts.Last is positive.
+// A trailing partial line and pending multi-line record are held back
+// unless final.
+func (src *Source) Load(ctx context.Context, opts LoadOptions, final bool, insert Insert) (err error) {
+
+	_, err = src.load(ctx, opts, final, insert)
+	return
+}
+
+// Follow polls for records appended to the source, until ctx is done,
+// reopening when the file is rotated or truncated, and inserting those
+// within since and until of opts.
+// Errors are logged rather than ending the follow.
+func (src *Source) Follow(ctx context.Context, lgr Logger, opts LoadOptions, insert Insert) {
+
+	opts.Last = 0
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := src.reopen(ctx, opts, insert)
+		if err != nil {
+			lgr.Error(ctx, "failed to reopen log file", err, "path", src.path)
+			continue
+		}
+
+		count, err := src.load(ctx, opts, false, insert)
+		if err != nil {
+			lgr.Error(ctx, "failed to load followed lines", err, "path", src.path)
+			continue
+		}
+
+		if count == 0 {
+			// quiet, so a pending multi-line record is complete
+			err = insert(ctx, src.path, within(opts, src.decoder.Flush()))
+			if err != nil {
+				lgr.Error(ctx, "failed to insert followed record", err, "path", src.path)
+			}
+		}
+	}
+}
+
+// Origins records the source each line was loaded from, by the id of the
+// first of each run of lines from it, for stores that are Sourcers.
+type Origins struct {
+	mu   sync.Mutex
+	runs []origin
+}
+
+// Add count lines from the source at path, the first with id first.
+func (ors *Origins) Add(path string, first int64, count int) {
+
+	ors.mu.Lock()
+	defer ors.mu.Unlock()
+
+	if count == 0 || len(ors.runs) > 0 && ors.runs[len(ors.runs)-1].path == path {
+		return
+	}
+	ors.runs = append(ors.runs, origin{first: first, path: path})
+}
+
+// Path of the source of the line with id.
+func (ors *Origins) Path(id string) (path string, err error) {
+
+	num, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse id %s", id)
+		return
+	}
+
+	ors.mu.Lock()
+	defer ors.mu.Unlock()
+
+	idx := sort.Search(len(ors.runs), func(i int) bool { return ors.runs[i].first > num })
+	if idx == 0 || num < 1 {
+		err = errors.Errorf("no source of line %s", id)
+		return
+	}
+	path = ors.runs[idx-1].path
+	return
+}
+
+// unexported
+
+type origin struct {
+	first int64
+	path  string
+}
+
+func (src *Source) load(ctx context.Context, opts LoadOptions, final bool, insert Insert) (count int, err error) {
+
+	last := opts.Last
+
+	var lines []string
+	var recs []Record
+	for {
+		err = ctx.Err()
+		if err != nil {
+			return
+		}
+
+		lines, err = src.readLines(batchSize)
+		if err != nil {
+			return
+		}
+		if src.tracker != nil {
+			src.tracker.Read(src.read)
+		}
+		if len(lines) == 0 {
+			break
+		}
+		count += len(lines)
+
+		for _, line := range lines {
+			recs = append(recs, within(opts, src.decoder.Decode(line))...)
+		}
+
+		if last > 0 {
+			if len(recs) > last {
+				recs = recs[len(recs)-last:]
+			}
+			continue
+		}
+
+		err = src.insert(ctx, insert, recs)
+		if err != nil {
+			return
+		}
+		recs = nil
+	}
+
+	if final {
+		if src.partial != "" {
+			recs = append(recs, within(opts, src.decoder.Decode(strings.TrimRight(src.partial, "\r\n")))...)
+			src.partial = ""
+		}
+		recs = append(recs, within(opts, src.decoder.Flush())...)
+	}
+	if last > 0 && len(recs) > last {
+		recs = recs[len(recs)-last:]
+	}
+
+	if len(recs) > 0 {
+		err = src.insert(ctx, insert, recs)
+	}
+	return
+}
+
+// within filters records to those opts includes
+func within(opts LoadOptions, recs []Record) []Record {
+
+	if opts.Since.IsZero() && opts.Until.IsZero() {
+		return recs
+	}
+
+	kept := recs[:0]
+	for _, rec := range recs {
+		if opts.Includes(rec) {
+			kept = append(kept, rec)
+		}
+	}
+	return kept
+}
+
+func (src *Source) insert(ctx context.Context, insert Insert, recs []Record) (err error) {
+
+	err = insert(ctx, src.path, recs)
+	if err == nil && src.tracker != nil {
+		src.tracker.Ingest(len(recs))
+	}
+	return
+}
+
+// readLines reads up to max complete lines, holding back a trailing partial line.
+func (src *Source) readLines(max int) (lines []string, err error) {
+
+	for len(lines) < max {
+		var chunk string
+		chunk, err = src.rdr.ReadString('\n')
+		src.partial += chunk
+		src.read += int64(len(chunk))
+
+		if err == io.EOF {
+			err = nil
+			return
+		}
+		if err != nil {
+			err = errors.Wrapf(err, "failed to read line")
+			return
+		}
+
+		line := strings.TrimRight(src.partial, "\r\n")
+		src.partial = ""
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
+
+// reopen the path when it's no longer the file, inserting what's left of
+// the old file first, or when the file was truncated, dropping any partial
+// line.
+func (src *Source) reopen(ctx context.Context, opts LoadOptions, insert Insert) (err error) {
+
+	pathInfo, err := os.Stat(src.path)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to stat path")
+		return
+	}
+	fileInfo, err := src.file.Stat()
+	if err != nil {
+		err = errors.Wrapf(err, "failed to stat file")
+		return
+	}
+	offset, err := src.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get file offset")
+		return
+	}
+
+	rotated := !os.SameFile(pathInfo, fileInfo)
+	if !rotated && pathInfo.Size() >= offset {
+		return
+	}
+
+	if rotated {
+		// lines written before the rotation, the last even if unterminated
+		_, err = src.load(ctx, opts, true, insert)
+		if err != nil {
+			return
+		}
+	}
+
+	file, err := os.Open(src.path)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to open log file")
+		return
+	}
+
+	src.file.Close()
+	src.file = file
+	src.rdr.Reset(file)
+	src.partial = ""
+	src.read = 0
+	return
+}
@@ -0,0 +1,178 @@
+package parcours
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+// statsGroups is how many groups are summarized, the largest
+const statsGroups = 100
+
+// Stats of a numeric field over lines in a group.
+type Stats struct {
+	Group string  `json:"group"`
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+}
+
+// Summarizer is implemented by stores that summarize numeric fields.
+type Summarizer interface {
+	// Summarize field over lines in view with a numeric value for it,
+	// grouped by the value of by unless empty, largest groups first and at
+	// most limit of them
+	Summarize(ctx context.Context, field, by string, limit int) (stats []Stats, err error)
+}
+
+// Summary of a field in view, shown below it.
+type Summary struct {
+	Field string
+	By    string
+	Stats []Stats
+}
+
+// RenderSummary renders stats of a field as a table, a row per group.
+func RenderSummary(summary Summary, width int) string {
+
+	group := summary.By
+	if group == "" {
+		group = "all"
+	}
+	fields := []Field{{Name: group, Type: "VARCHAR"}, {Name: "count", Type: "BIGINT"}}
+	for _, name := range []string{"min", "max", "mean", "p50", "p90", "p99"} {
+		fields = append(fields, Field{Name: name, Type: "DOUBLE"})
+	}
+
+	lines := make([]Line, len(summary.Stats))
+	for i, stats := range summary.Stats {
+		lines[i] = Line{{Raw: stats.Group}, {Raw: int64(stats.Count)}}
+		for _, num := range []float64{stats.Min, stats.Max, stats.Mean, stats.P50, stats.P90, stats.P99} {
+			lines[i] = append(lines[i], Value{Raw: formatNumber(num)})
+		}
+	}
+
+	header := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).
+		Render(fmt.Sprintf("%s by %s | s close | S group by", summary.Field, group))
+	if len(lines) == 0 {
+		return header + "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("no numbers in view")
+	}
+	return header + "\n" + RenderTable(fields, lines[:min(len(lines), pageSize/2)], -1, -1, width, AutoLayout(fields, lines), time.UTC, nil)
+}
+
+// Quantile of sorted numbers, interpolating linearly between them.
+func Quantile(sorted []float64, q float64) float64 {
+
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := q * float64(len(sorted)-1)
+	lo := int(pos)
+	hi := min(lo+1, len(sorted)-1)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// SummarizeGroups of numbers, for stores that can't summarize in a query,
+// largest groups first and at most limit of them. The numbers are sorted
+// in place.
+func SummarizeGroups(groups map[string][]float64, limit int) (stats []Stats) {
+
+	for group, nums := range groups {
+		slices.Sort(nums)
+		st := Stats{Group: group, Count: len(nums), Min: nums[0], Max: nums[len(nums)-1]}
+		for _, num := range nums {
+			st.Mean += num
+		}
+		st.Mean /= float64(len(nums))
+		st.P50 = Quantile(nums, 0.5)
+		st.P90 = Quantile(nums, 0.9)
+		st.P99 = Quantile(nums, 0.99)
+		stats = append(stats, st)
+	}
+	slices.SortFunc(stats, func(a, b Stats) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Group, b.Group))
+	})
+	stats = stats[:min(len(stats), limit)]
+	return
+}
+
+// unexported
+
+type summaryMsg struct {
+	summary Summary
+	err     error
+}
+
+// formatNumber briefly, to at most four decimal places
+func formatNumber(num float64) string {
+
+	text := fmt.Sprintf("%.4f", num)
+	if strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	return text
+}
+
+// summarize the selected column, grouped by by unless empty, when the
+// store summarizes
+func (m Model) summarize(by string) tea.Cmd {
+	summarizer, ok := m.Store.(Summarizer)
+	field := m.selectedField()
+	if !ok || field == "" {
+		return nil
+	}
+	return func() tea.Msg {
+		stats, err := summarizer.Summarize(m.loads.ctx, field, by, statsGroups)
+		return summaryMsg{summary: Summary{Field: field, By: by, Stats: stats}, err: err}
+	}
+}
+
+// selectedField is the field of the selected column, if any
+func (m Model) selectedField() string {
+	columns := m.layout().Visible()
+	if m.SelectedColumn < 0 || m.SelectedColumn >= len(columns) {
+		return ""
+	}
+	return columns[m.SelectedColumn].Field
+}
+
+// openGroupBy opens a prompt for the field to group stats by
+func (m Model) openGroupBy() (Model, tea.Cmd) {
+	if _, ok := m.Store.(Summarizer); !ok {
+		return m, nil
+	}
+	by := ""
+	if m.Summary != nil {
+		by = m.Summary.By
+	}
+	m.Prompt, m.prompting = NewPrompt("group by> ", by), statsPrompt
+	return m, nil
+}
+
+// moveColumn selects the column delta columns along, as far as they go,
+// summarizing it instead when a summary is open
+func (m Model) moveColumn(delta int) (Model, tea.Cmd) {
+	columns := m.layout().Visible()
+	m.SelectedColumn = max(min(m.SelectedColumn+delta, len(columns)-1), 0)
+	if m.Summary == nil {
+		return m, nil
+	}
+	return m, m.summarize(m.Summary.By)
+}
+
+// Visible columns, neither hidden nor demoted.
+func (layout *Layout) Visible() []Column {
+	return slices.DeleteFunc(slices.Clone(layout.Columns), func(col Column) bool {
+		return col.Hidden || col.Demote
+	})
+}
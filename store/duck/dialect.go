@@ -0,0 +1,49 @@
+package duck
+
+import (
+	"fmt"
+
+	"parcours"
+)
+
+// dialect of DuckDB for rendering views, with levels an enum and patterns
+// and raw JSON kept in tables of their own
+type dialect struct{}
+
+func (dialect) Same() string {
+	return "IS NOT DISTINCT FROM"
+}
+
+func (dialect) Column(name string) (expr string, ok bool) {
+
+	if name != parcours.PatternField {
+		return
+	}
+	expr, ok = "(SELECT pattern FROM logs_pattern WHERE logs_pattern.id = logs.id)", true
+	return
+}
+
+func (dialect) Extract(path string) (expr string, args []any) {
+
+	expr = "(SELECT json_extract_string(raw, ?) FROM logs_raw WHERE logs_raw.id = logs.id)"
+	args = []any{path}
+	return
+}
+
+// Arg casts levels so they compare by severity
+func (dialect) Arg(name string, val any) (arg string, bound any, err error) {
+
+	arg, bound = "?", val
+	if lvl, ok := val.(parcours.Level); ok && name == "level" {
+		arg, bound = "?::"+levelType, lvl.String()
+	}
+	return
+}
+
+func (dialect) Contains(expr string) string {
+	return fmt.Sprintf("contains(%s::VARCHAR, ?)", expr)
+}
+
+func (dialect) Match(expr string) string {
+	return fmt.Sprintf("regexp_matches(%s::VARCHAR, ?)", expr)
+}
@@ -4,32 +4,70 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
 
+	_ "github.com/marcboeker/go-duckdb"
 	"github.com/pkg/errors"
 
 	"parcours"
+	"parcours/store/sqlview"
 )
 
 // Todo: use uptodate lib from duckdb in main
 
 type Duck struct {
-	db     *sql.DB
-	logger parcours.Logger
-	filter parcours.Filter
-	sorts  []parcours.Sort
+	db        *sql.DB
+	logger    parcours.Logger
+	decoder   *parcours.Decoder
+	filter    parcours.Filter
+	sorts     []parcours.Sort
+	tail      parcours.Broadcast
+	progress  parcours.Tracker
+	mu        sync.Mutex
+	promoted  []string
+	lastID    int64
+	retention Retention
+	// retaining starts eviction with the first follow
+	retaining sync.Once
+	miner     *parcours.Miner
+	origins   parcours.Origins
+	// lines around matches, taken up to surrounded
+	surround   parcours.Surround
+	surrounded int64
 }
 
-func New(lgr parcours.Logger) (dk *Duck, err error) {
+// New creates a Duck store, decoding ndjson when dec is nil.
+func New(dec *parcours.Decoder, lgr parcours.Logger) (dk *Duck, err error) {
 
-	db, err := sql.Open("duckdb", "")
+	if dec == nil {
+		dec, err = parcours.NewDecoder(nil, nil)
+		if err != nil {
+			return
+		}
+	}
+
+	// ad-hoc queries, read-only as they are, may not read files either,
+	// with the setting locked against them
+	db, err := sql.Open("duckdb", "?enable_external_access=false&lock_configuration=true")
 	if err != nil {
 		err = errors.Wrapf(err, "failed to open memo duck")
 		return
 	}
 
+	err = createTables(db)
+	if err != nil {
+		return
+	}
+
 	dk = &Duck{
-		db:     db,
-		logger: lgr,
+		db:      db,
+		logger:  lgr,
+		decoder: dec,
+		miner:   parcours.NewMiner(),
 	}
 
 	return
@@ -40,69 +78,195 @@ func (dk *Duck) Close() {
 }
 
 // Load a file
-func (dk *Duck) Load(path string, last int) (err error) {
-	err = loadDualTable(dk.db, path)
+func (dk *Duck) Load(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	src, err := parcours.OpenSource(path, dk.decoder)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	defer dk.progress.Finish()
+
+	err = dk.load(ctx, src, opts, true)
+	return
+}
+
+// Follow a file, loading what's there and then polling for more
+func (dk *Duck) Follow(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	src, err := parcours.OpenSource(path, dk.decoder)
+	if err != nil {
+		return
+	}
+	defer dk.progress.Finish()
+
+	err = dk.load(ctx, src, opts, false)
+	if err != nil {
+		src.Close()
+		return
+	}
+
+	go func() {
+		defer src.Close()
+		src.Follow(ctx, dk.logger, opts, dk.insert)
+	}()
+	dk.retaining.Do(func() { go dk.retain(ctx) })
 	return
 }
 
-// Follow a file
-func (dk *Duck) Follow(ctx context.Context, path string, last int) (err error) {
-	// Todo:
-	return errors.New("not implemented")
+// Progress of a load or promotion
+func (dk *Duck) Progress() parcours.Progress {
+	return dk.progress.Progress()
 }
 
 // Promote a field
-func (dk *Duck) Promote(field string) (err error) {
-	err = PromoteField(dk.db, field)
+func (dk *Duck) Promote(ctx context.Context, field string) (err error) {
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+
+	dk.progress.Start("promoting "+field, 0, dk.lastID)
+	defer dk.progress.Finish()
+
+	err = PromoteField(ctx, dk.db, field, &dk.progress)
+	if err != nil {
+		return
+	}
+
+	dk.progress.Start("indexing "+field, 0, 0)
+	err = IndexField(ctx, dk.db, field)
 	if err != nil {
 		return
 	}
-	err = IndexField(dk.db, field)
+
+	if !slices.Contains(dk.promoted, field) {
+		dk.promoted = append(dk.promoted, field)
+	}
 	return
 }
 
 // SetView Filter and Sort(s)
-func (dk *Duck) SetView(filter parcours.Filter, sorts []parcours.Sort) (err error) {
+func (dk *Duck) SetView(ctx context.Context, filter parcours.Filter, sorts []parcours.Sort) (err error) {
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+
 	dk.filter = filter
 	dk.sorts = sorts
-	return nil
+	if !dk.surround.Active() {
+		return
+	}
+	err = dk.surroundLines(ctx)
+	return
 }
 
 // GetView fields and count
-func (dk *Duck) GetView() (fields []parcours.Field, count int, err error) {
-	// Get fields from schema
-	rawFields, err := getFields(dk.db)
+func (dk *Duck) GetView(ctx context.Context) (fields []parcours.Field, count int, err error) {
+
+	err = dk.refreshSurround(ctx)
 	if err != nil {
-		return nil, 0, err
+		return
 	}
 
-	// Convert to parcours.Field
-	fields = make([]parcours.Field, len(rawFields))
-	for i, f := range rawFields {
-		fields[i] = parcours.Field{
-			Name: f.Name,
-			Type: f.Type,
-		}
+	fields, err = dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	filter, _, surround := dk.viewed()
+	vw := sqlview.New(dialect{}, fields, surround)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+	if surround {
+		fields = parcours.SurroundFields(fields)
 	}
 
-	// Get count (TODO: apply filter)
-	err = dk.db.QueryRow("SELECT COUNT(*) FROM logs").Scan(&count)
+	err = dk.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM logs WHERE "+cond, vw.Args...).Scan(&count)
 	if err != nil {
 		err = errors.Wrapf(err, "failed to count logs")
-		return nil, 0, err
+		return
 	}
 
-	return fields, count, nil
+	return
 }
 
 // GetPage of log lines
-func (dk *Duck) GetPage(offset, size int) (lines []parcours.Line, err error) {
+func (dk *Duck) GetPage(ctx context.Context, offset, size int) (lines []parcours.Line, err error) {
+
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	filter, sorts, surround := dk.viewed()
+	vw := sqlview.New(dialect{}, fields, surround)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+	order := vw.OrderBy(sorts, false)
+
+	query := fmt.Sprintf("SELECT %s FROM logs WHERE %s %s LIMIT %d OFFSET %d",
+		vw.Selection("logs.*"), cond, order, size, offset)
+
+	lines, err = queryLines(ctx, dk.db, query, vw.Args...)
+	return
+}
 
-	// Todo: apply filter and sort
+// GetPageAt returns lines after or before cursor, keyed on the sort fields
+// and id rather than offset
+func (dk *Duck) GetPageAt(ctx context.Context, cursor parcours.Cursor, size int) (lines []parcours.Line, err error) {
 
-	query := fmt.Sprintf("SELECT * FROM logs LIMIT %d OFFSET %d", size, offset)
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	filter, sorts, surround := dk.viewed()
+	vw := sqlview.New(dialect{}, fields, surround)
+	query, err := vw.Seek("logs.*", filter, sorts, cursor, size)
+	if err != nil {
+		return
+	}
+
+	lines, err = queryLines(ctx, dk.db, query, vw.Args...)
+	if cursor.Before {
+		slices.Reverse(lines)
+	}
+	return
+}
+
+// Locate the offset in the view of the line with id, or of its nearest
+// neighbour when it's filtered out
+func (dk *Duck) Locate(ctx context.Context, id string) (offset int, err error) {
+
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	filter, sorts, surround := dk.viewed()
+	vw := sqlview.New(dialect{}, fields, surround)
+	query, err := vw.Locate(filter, sorts, id)
+	if err != nil {
+		return
+	}
 
-	rows, err := dk.db.Query(query)
+	var count int
+	err = dk.db.QueryRowContext(ctx, query, vw.Args...).Scan(&offset, &count)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to locate line %s", id)
+		return
+	}
+
+	offset = max(min(offset, count-1), 0)
+	return
+}
+
+func queryLines(ctx context.Context, db *sql.DB, query string, args ...any) (lines []parcours.Line, err error) {
+
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		err = errors.Wrapf(err, "failed to query logs")
 		return
@@ -152,123 +316,128 @@ func scanRow(rows *sql.Rows, columnCount int) ([]any, error) {
 	return vals, err
 }
 
+// Source of the line with id
+func (dk *Duck) Source(ctx context.Context, id string) (path string, err error) {
+	return dk.origins.Path(id)
+}
+
 // GetJson returns raw json for a line
-func (dk *Duck) GetJson(id string) (data map[string]any, err error) {
+func (dk *Duck) GetJson(ctx context.Context, id string) (data map[string]any, err error) {
 
-	query := "SELECT raw FROM logs_raw WHERE id = ?"
+	// as text, so that numbers are decoded intact
+	query := "SELECT raw::VARCHAR FROM logs_raw WHERE id = ?"
 
-	var raw any
-	err = dk.db.QueryRow(query, id).Scan(&raw)
+	var raw string
+	err = dk.db.QueryRowContext(ctx, query, id).Scan(&raw)
 	if err != nil {
 		err = errors.Wrapf(err, "failed to query raw JSON")
 		return
 	}
 
-	var ok bool
-	data, ok = raw.(map[string]any)
-	if !ok {
-		err = errors.Errorf("expected map[string]any from driver, got %T", raw)
-	}
+	data, err = parcours.UnmarshalRaw([]byte(raw))
 	return
 }
 
-// Tail streams log lines
+// Tail streams log lines as they are inserted, until ctx is done
 func (dk *Duck) Tail(ctx context.Context) (lines <-chan parcours.Line, err error) {
-	// Todo:
-	return nil, errors.New("not implemented")
+
+	lines = dk.tail.Subscribe(ctx)
+	return
 }
 
 // unexported
 
-func loadDualTable(db *sql.DB, logFile string) (err error) {
-	// Todo: ID alignment issue
-	// Both tables use ROW_NUMBER() OVER () assuming both functions read file in same order
-	// This works but is implicit - better approaches:
-	//   1. Use MD5(raw) as content hash for joining
-	//   2. Use natural key from logs (ts + some unique field)
-	//   3. Read file once in Go, insert to both tables with same ID
-	// Current approach is simple and works for single-file bulk loads
-
-	// Table 1: Structured data with ONLY core fields
-	// Use columns parameter to extract only ts, level, msg
-	// Other fields stay in raw JSON for controlled promotion later
-	// Todo: Consider ENUM type for level (info, debug, warn, error, etc)
-	createStructured := fmt.Sprintf(`
-		CREATE TABLE logs AS
-		SELECT
-			ROW_NUMBER() OVER () as id,
-			ts as timestamp,
-			level,
-			msg as message
-		FROM read_json('%s',
-			columns={ts: 'TIMESTAMP', level: 'VARCHAR', msg: 'VARCHAR'},
-			format='newline_delimited',
-			maximum_object_size=16777216)
-	`, logFile)
-
-	_, err = db.Exec(createStructured)
+// PromoteField promotes a field from logs_raw to a column in logs table,
+// backfilling in chunks so that progress can be tracked
+func PromoteField(ctx context.Context, db *sql.DB, fieldName string, tracker *parcours.Tracker) (err error) {
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(
+		"ALTER TABLE logs ADD COLUMN IF NOT EXISTS %s VARCHAR",
+		sqlview.QuoteIdent(fieldName)))
 	if err != nil {
-		err = errors.Wrapf(err, "failed to create table")
+		err = errors.Wrapf(err, "failed to add column")
 		return
 	}
 
-	// Table 2: Raw JSON
-	createRaw := fmt.Sprintf(`
-		CREATE TABLE logs_raw AS
-		SELECT
-			ROW_NUMBER() OVER () as id,
-			json_text::JSON as raw
-		FROM read_json_objects('%s', format='newline_delimited') AS t(json_text)
-	`, logFile)
-
-	_, err = db.Exec(createRaw)
+	var maxID int64
+	err = db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM logs").Scan(&maxID)
 	if err != nil {
-		err = errors.Wrapf(err, "failed to create table")
+		err = errors.Wrapf(err, "failed to query last id")
 		return
 	}
 
-	_, err = db.Exec("CREATE INDEX idx_timestamp ON logs(timestamp)")
-	err = errors.Wrapf(err, "failed to create index")
+	// Step 2: Backfill from logs_raw (extracting from JSON)
+	for lo := int64(0); lo < maxID; lo += backfillSize {
+		_, err = db.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE logs
+			SET %s = json_extract_string(logs_raw.raw, ?)
+			FROM logs_raw
+			WHERE logs.id = logs_raw.id AND logs.id > ? AND logs.id <= ?
+		`, sqlview.QuoteIdent(fieldName)), sqlview.JsonPath(fieldName), lo, lo+backfillSize)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to backfill column")
+			return
+		}
+
+		if tracker != nil {
+			tracker.Ingest(int(min(backfillSize, maxID-lo)))
+		}
+	}
 	return
 }
 
-// PromoteField promotes a field from logs_raw to a column in logs table
-func PromoteField(db *sql.DB, fieldName string) (err error) {
+func IndexField(ctx context.Context, db *sql.DB, fieldName string) (err error) {
 
-	_, err = db.Exec(fmt.Sprintf(
-		"ALTER TABLE logs ADD COLUMN IF NOT EXISTS %s VARCHAR",
-		fieldName))
-	if err != nil {
-		err = errors.Wrapf(err, "failed to add column")
-		return
+	_, err = db.ExecContext(ctx, fmt.Sprintf(
+		"CREATE INDEX IF NOT EXISTS %s ON logs(%s)",
+		sqlview.QuoteIdent(indexName(fieldName)), sqlview.QuoteIdent(fieldName)))
+	err = errors.Wrapf(err, "failed to index column")
+	return
+}
+
+// indexName for a field's column, from its word characters, with a hash of
+// the name when others were dropped to keep it apart from similar names
+func indexName(field string) string {
+
+	safe := strings.Map(func(r rune) rune {
+		if r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, field)
+	if safe == field {
+		return "idx_" + safe
 	}
 
-	// Step 2: Backfill from logs_raw (extracting from JSON)
-	_, err = db.Exec(fmt.Sprintf(`
-		UPDATE logs
-		SET %s = json_extract_string(logs_raw.raw, '$.%s')
-		FROM logs_raw
-		WHERE logs.id = logs_raw.id
-	`, fieldName, fieldName))
-	err = errors.Wrapf(err, "failed to backfill column")
-	return
+	hash := fnv.New32a()
+	hash.Write([]byte(field))
+	return fmt.Sprintf("idx_%s_%08x", safe, hash.Sum32())
 }
 
-func IndexField(db *sql.DB, fieldName string) (err error) {
+// fields of the logs table, as parcours fields
+func (dk *Duck) fields(ctx context.Context) (fields []parcours.Field, err error) {
 
-	_, err = db.Exec(fmt.Sprintf(
-		"CREATE INDEX IF NOT EXISTS idx_%s ON logs(%s)",
-		fieldName, fieldName))
-	err = errors.Wrapf(err, "failed to index column")
+	rawFields, err := getFields(ctx, dk.db)
+	if err != nil {
+		return
+	}
+
+	fields = make([]parcours.Field, len(rawFields))
+	for i, f := range rawFields {
+		fields[i] = parcours.Field{
+			Name: f.Name,
+			Type: f.Type,
+		}
+	}
 	return
 }
 
-func getFields(db *sql.DB) (fields []struct {
+func getFields(ctx context.Context, db *sql.DB) (fields []struct {
 	Name string
 	Type string
 }, err error) {
 
-	rows, err := db.Query(`
+	rows, err := db.QueryContext(ctx, `
 		SELECT column_name, data_type
 		FROM information_schema.columns
 		WHERE table_name = 'logs'
@@ -0,0 +1,265 @@
+package duck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"parcours"
+	"parcours/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+
+	storetest.Run(t, func(t *testing.T, dec *parcours.Decoder) parcours.Store {
+
+		dk, err := New(dec, storetest.Logger{T: t})
+		if err != nil {
+			t.Fatalf("failed to create duck: %+v", err)
+		}
+		t.Cleanup(dk.Close)
+
+		return dk
+	})
+}
+
+func TestRetention(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		retention Retention
+		count     int
+		firstID   string
+	}{
+		{name: "rows", retention: Retention{Rows: 5}, count: 5, firstID: "15"},
+		{name: "age", retention: Retention{Age: 10 * time.Minute}, count: 7, firstID: "13"},
+		{name: "rows within age", retention: Retention{Rows: 3, Age: 10 * time.Minute}, count: 3, firstID: "17"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dk, err := New(nil, storetest.Logger{T: t})
+			if err != nil {
+				t.Fatalf("failed to create duck: %+v", err)
+			}
+			t.Cleanup(dk.Close)
+			dk.Retain(tc.retention)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			defer cancel()
+			err = dk.Follow(ctx, storetest.Fixture("smar.log"), parcours.LoadOptions{})
+			if err != nil {
+				t.Fatalf("failed to follow: %+v", err)
+			}
+
+			var count int
+			for range 50 {
+				_, count, err = dk.GetView(ctx)
+				if err != nil {
+					t.Fatalf("failed to get view: %+v", err)
+				}
+				if count == tc.count {
+					break
+				}
+				time.Sleep(100 * time.Millisecond)
+			}
+			if count != tc.count {
+				t.Fatalf("expected %d rows retained, got %d", tc.count, count)
+			}
+
+			lines, err := dk.GetPage(ctx, 0, 1)
+			if err != nil {
+				t.Fatalf("failed to get page: %+v", err)
+			}
+			if id := lines[0][0].String(); id != tc.firstID {
+				t.Errorf("expected first retained id %s, got %s", tc.firstID, id)
+			}
+
+			_, err = dk.GetJson(ctx, "1")
+			if err == nil {
+				t.Errorf("expected raw record of evicted row to be gone")
+			}
+		})
+	}
+}
+
+func TestQuery(t *testing.T) {
+
+	dk, err := New(nil, storetest.Logger{T: t})
+	if err != nil {
+		t.Fatalf("failed to create duck: %+v", err)
+	}
+	t.Cleanup(dk.Close)
+
+	err = dk.Load(t.Context(), storetest.Fixture("smar.log"), parcours.LoadOptions{})
+	if err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+
+	fields, lines, err := dk.Query(t.Context(), `
+		WITH counts AS (SELECT level, COUNT(*) AS n FROM logs GROUP BY level)
+		SELECT level::VARCHAR AS level, n FROM counts ORDER BY n DESC, level`)
+	if err != nil {
+		t.Fatalf("failed to query: %+v", err)
+	}
+	if len(fields) != 2 || fields[0].Name != "level" || fields[1].Name != "n" {
+		t.Errorf("expected fields level and n, got %v", fields)
+	}
+	if len(lines) == 0 || lines[0][0].String() != "info" {
+		t.Errorf("expected info to be most common, got %v", lines)
+	}
+
+	for _, query := range []string{
+		"DELETE FROM logs",
+		"CREATE TABLE copied AS SELECT * FROM logs",
+		"SELECT 1; DROP TABLE logs",
+		"SELECT nonsense FROM",
+		// reading files, as a server allowing queries would
+		"SELECT * FROM read_text('" + storetest.Fixture("smar.log") + "')",
+		"SELECT * FROM read_csv('" + storetest.Fixture("smar.log") + "')",
+	} {
+		_, _, err = dk.Query(t.Context(), query)
+		if err == nil {
+			t.Errorf("expected %q to be refused", query)
+		}
+	}
+
+	// nor may files be let in again
+	_, err = dk.db.Exec("SET enable_external_access = true")
+	if err == nil {
+		t.Errorf("expected external access to stay disabled")
+	}
+
+	_, count, err := dk.GetView(t.Context())
+	if err != nil {
+		t.Fatalf("failed to get view: %+v", err)
+	}
+	if count != 19 {
+		t.Errorf("expected refused queries to leave 19 lines, got %d", count)
+	}
+}
+
+func TestPromoteKeys(t *testing.T) {
+
+	dk, err := New(nil, storetest.Logger{T: t})
+	if err != nil {
+		t.Fatalf("failed to create duck: %+v", err)
+	}
+	t.Cleanup(dk.Close)
+
+	// promoted ahead of loading, extracted as lines are inserted, and after,
+	// backfilled
+	keys := []string{"user name", "it's", "x VARCHAR; --", "http.status"}
+	for _, key := range keys[:2] {
+		err = dk.Promote(t.Context(), key)
+		if err != nil {
+			t.Fatalf("failed to promote %q: %+v", key, err)
+		}
+	}
+	err = dk.Load(t.Context(), storetest.Fixture("keys.log"), parcours.LoadOptions{})
+	if err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+	for _, key := range keys[2:] {
+		err = dk.Promote(t.Context(), key)
+		if err != nil {
+			t.Fatalf("failed to promote %q: %+v", key, err)
+		}
+	}
+
+	fields, _, err := dk.GetView(t.Context())
+	if err != nil {
+		t.Fatalf("failed to get view: %+v", err)
+	}
+	if len(fields) != 8 || fields[4].Name != "user name" || fields[6].Name != "x VARCHAR; --" {
+		t.Fatalf("expected promoted keys as fields, got %v", fields)
+	}
+
+	lines, err := dk.GetPage(t.Context(), 0, 2)
+	if err != nil {
+		t.Fatalf("failed to get page: %+v", err)
+	}
+	expected := []string{"bob", "again", "twice", "503"}
+	for i, val := range expected {
+		if got := lines[1][4+i].String(); got != val {
+			t.Errorf("expected %s of %q, got %q", keys[i], val, got)
+		}
+	}
+
+	err = dk.SetView(t.Context(), parcours.Filter{Op: parcours.Eq, Field: "it's", Value: "quoted"}, nil)
+	if err != nil {
+		t.Fatalf("failed to set view: %+v", err)
+	}
+	_, count, err := dk.GetView(t.Context())
+	if err != nil {
+		t.Fatalf("failed to get view: %+v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 line filtered on a quoted key, got %d", count)
+	}
+}
+
+func TestInsertCancelled(t *testing.T) {
+
+	dk, err := New(nil, storetest.Logger{T: t})
+	if err != nil {
+		t.Fatalf("failed to create duck: %+v", err)
+	}
+	t.Cleanup(dk.Close)
+
+	// as left staged by an insert that failed
+	_, err = dk.db.Exec("INSERT INTO logs_stage VALUES (1, NULL, 'info', 'stale', '{}', 1)")
+	if err != nil {
+		t.Fatalf("failed to stage: %+v", err)
+	}
+
+	var b strings.Builder
+	for i := range 200000 {
+		fmt.Fprintf(&b, `{"ts":"2025-11-13T20:00:00Z","level":"info","msg":"line %d"}`+"\n", i)
+	}
+	path := filepath.Join(t.TempDir(), "big.log")
+	err = os.WriteFile(path, []byte(b.String()), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write: %+v", err)
+	}
+
+	// cancelled once the first batch is in, partway through the rest
+	ctx, cancel := context.WithCancel(t.Context())
+	go func() {
+		defer cancel()
+		for dk.Progress().Rows == 0 && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	err = dk.Load(ctx, path, parcours.LoadOptions{})
+	if err == nil {
+		t.Fatalf("expected cancelled load to fail")
+	}
+
+	err = dk.Load(t.Context(), storetest.Fixture("smar.log"), parcours.LoadOptions{})
+	if err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+
+	for _, table := range []string{"logs", "logs_raw", "logs_pattern"} {
+		var count, distinct, last int64
+		err = dk.db.QueryRow(fmt.Sprintf("SELECT COUNT(*), COUNT(DISTINCT id), MAX(id) FROM %s", table)).Scan(&count, &distinct, &last)
+		if err != nil {
+			t.Fatalf("failed to count %s: %+v", table, err)
+		}
+		if count != distinct || count != last {
+			t.Errorf("expected unique ids 1 to %d in %s, got %d ids, %d distinct", last, table, count, distinct)
+		}
+	}
+	lines, err := dk.GetPage(t.Context(), 0, 1)
+	if err != nil {
+		t.Fatalf("failed to get page: %+v", err)
+	}
+	if msg := lines[0][3].String(); msg != "line 0" {
+		t.Errorf("expected first line loaded first, not what was staged, got %q", msg)
+	}
+}
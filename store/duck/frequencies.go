@@ -0,0 +1,21 @@
+package duck
+
+import (
+	"context"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// Frequencies of a field's values among lines matching filter, most
+// frequent first
+func (dk *Duck) Frequencies(ctx context.Context, filter parcours.Filter, field string, limit int) (freqs []parcours.Frequency, total int, err error) {
+
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	freqs, total, err = sqlview.New(dialect{}, fields, false).Frequencies(ctx, dk.db, filter, field, limit)
+	return
+}
@@ -0,0 +1,228 @@
+package duck
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/marcboeker/go-duckdb"
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+const (
+	levelType    = "log_level"
+	backfillSize = 100000
+)
+
+// load from a source, tracking progress, and index once loaded
+func (dk *Duck) load(ctx context.Context, src *parcours.Source, opts parcours.LoadOptions, final bool) (err error) {
+
+	dk.progress.Start("loading", src.Size(), 0)
+	src.Track(&dk.progress)
+	defer src.Track(nil)
+
+	err = src.Load(ctx, opts, final, dk.insert)
+	if err != nil {
+		return
+	}
+
+	dk.progress.Start("indexing", 0, 0)
+	_, err = dk.db.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_timestamp ON logs(timestamp)")
+	err = errors.Wrapf(err, "failed to create index")
+	return
+}
+
+// insert records into the tables with the same ids, mining the patterns
+// of their messages, appending to a staging table and moving from there.
+// The staging table is emptied before appending and after failing, so
+// records staged by a failed insert aren't moved with the next.
+func (dk *Duck) insert(ctx context.Context, path string, recs []parcours.Record) (err error) {
+
+	if len(recs) == 0 {
+		return
+	}
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+
+	patterns := make([]int64, len(recs))
+	for i, rec := range recs {
+		patterns[i] = dk.miner.Add(rec.Message)
+	}
+
+	err = clearStage(ctx, dk.db)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			// ctx being cancelled is likely why it failed
+			clearStage(context.WithoutCancel(ctx), dk.db)
+		}
+	}()
+
+	err = appendStage(ctx, dk.db, dk.lastID, recs, patterns)
+	if err != nil {
+		return
+	}
+
+	tx, err := dk.db.BeginTx(ctx, nil)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to begin insert")
+		return
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		"INSERT INTO logs (id, timestamp, level, message) SELECT id, timestamp, NULLIF(level, ''), message FROM logs_stage",
+		"INSERT INTO logs_raw SELECT id, raw::JSON FROM logs_stage",
+		"INSERT INTO logs_pattern SELECT id, pattern FROM logs_stage",
+	}
+	for _, stmt := range stmts {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to move staged records")
+			return
+		}
+	}
+
+	for _, field := range dk.promoted {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE logs
+			SET %s = json_extract_string(logs_stage.raw, ?)
+			FROM logs_stage
+			WHERE logs.id = logs_stage.id
+		`, sqlview.QuoteIdent(field)), sqlview.JsonPath(field))
+		if err != nil {
+			err = errors.Wrapf(err, "failed to extract promoted field %s", field)
+			return
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM logs_stage")
+	if err != nil {
+		err = errors.Wrapf(err, "failed to clear staged records")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		err = errors.Wrapf(err, "failed to commit insert")
+		return
+	}
+
+	prevID := dk.lastID
+	dk.lastID += int64(len(recs))
+	dk.origins.Add(path, prevID+1, len(recs))
+
+	if !dk.tail.Active() {
+		return
+	}
+
+	lines, err := queryLines(ctx, dk.db, "SELECT * FROM logs WHERE id > ? ORDER BY id", prevID)
+	if err != nil {
+		return
+	}
+	dk.tail.Publish(lines)
+	return
+}
+
+func createTables(db *sql.DB) (err error) {
+
+	// Level is an enum so that it sorts and compares by severity
+	levels := "'" + strings.Join(parcours.Levels, "', '") + "'"
+
+	// Core fields only, others stay in raw JSON for controlled promotion later
+	stmts := []string{
+		fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", levelType, levels),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS logs (id BIGINT, timestamp TIMESTAMP, level %s, message VARCHAR)", levelType),
+		"CREATE TABLE IF NOT EXISTS logs_raw (id BIGINT, raw JSON)",
+		"CREATE TABLE IF NOT EXISTS logs_pattern (id BIGINT, pattern BIGINT)",
+		"CREATE TABLE IF NOT EXISTS logs_stage (id BIGINT, timestamp TIMESTAMP, level VARCHAR, message VARCHAR, raw VARCHAR, pattern BIGINT)",
+	}
+
+	for _, stmt := range stmts {
+		_, err = db.Exec(stmt)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to create table")
+			return
+		}
+	}
+	return
+}
+
+func clearStage(ctx context.Context, db *sql.DB) (err error) {
+
+	_, err = db.ExecContext(ctx, "DELETE FROM logs_stage")
+	err = errors.Wrapf(err, "failed to clear staged records")
+	return
+}
+
+func appendStage(ctx context.Context, db *sql.DB, lastID int64, recs []parcours.Record, patterns []int64) (err error) {
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get conn for appender")
+		return
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(dc any) (err error) {
+
+		appender, err := duckdb.NewAppenderFromConn(dc.(driver.Conn), "", "logs_stage")
+		if err != nil {
+			return
+		}
+
+		for i, rec := range recs {
+			var raw string
+			raw, err = encodeRaw(rec.Data)
+			if err != nil {
+				appender.Close()
+				return
+			}
+
+			err = appender.AppendRow(lastID+int64(i)+1, nullTime(rec), rec.Level, rec.Message, raw, patterns[i])
+			if err != nil {
+				appender.Close()
+				return
+			}
+		}
+
+		err = appender.Close()
+		return
+	})
+	err = errors.Wrapf(err, "failed to append staged records")
+	return
+}
+
+func encodeRaw(data map[string]any) (raw string, err error) {
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	err = encoder.Encode(data)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to encode raw JSON")
+		return
+	}
+
+	raw = string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return
+}
+
+func nullTime(rec parcours.Record) driver.Value {
+
+	if rec.Timestamp.IsZero() {
+		return nil
+	}
+	return rec.Timestamp
+}
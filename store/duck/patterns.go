@@ -0,0 +1,26 @@
+package duck
+
+import (
+	"context"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// Patterns of lines in view, most frequent first
+func (dk *Duck) Patterns(ctx context.Context) (patterns []parcours.Pattern, err error) {
+
+	err = dk.refreshSurround(ctx)
+	if err != nil {
+		return
+	}
+
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	filter, _, surround := dk.viewed()
+	patterns, err = sqlview.New(dialect{}, fields, surround).Patterns(ctx, dk.db, filter, dk.miner)
+	return
+}
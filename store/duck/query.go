@@ -0,0 +1,91 @@
+package duck
+
+import (
+	"context"
+
+	"github.com/marcboeker/go-duckdb"
+	"github.com/pkg/errors"
+
+	"parcours"
+)
+
+// queryLimit caps the lines returned by an ad-hoc query
+const queryLimit = 10000
+
+// Query runs a single read-only SELECT against logs and logs_raw, returning
+// up to queryLimit lines, with its columns as fields.
+func (dk *Duck) Query(ctx context.Context, query string) (fields []parcours.Field, lines []parcours.Line, err error) {
+
+	conn, err := dk.db.Conn(ctx)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get connection")
+		return
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn any) (err error) {
+		return readOnly(driverConn.(*duckdb.Conn), query)
+	})
+	if err != nil {
+		return
+	}
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query")
+		return
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get query columns")
+		return
+	}
+	fields = make([]parcours.Field, len(types))
+	for i, typ := range types {
+		fields[i] = parcours.Field{Name: typ.Name(), Type: typ.DatabaseTypeName()}
+	}
+
+	for len(lines) < queryLimit && rows.Next() {
+		var vals []any
+		vals, err = scanRow(rows, len(fields))
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan row")
+			return
+		}
+
+		line := make(parcours.Line, len(vals))
+		for i, val := range vals {
+			line[i] = parcours.Value{Raw: val}
+		}
+		lines = append(lines, line)
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating rows")
+	return
+}
+
+// unexported
+
+// readOnly checks that query is a single select, by preparing it
+func readOnly(conn *duckdb.Conn, query string) (err error) {
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to prepare query")
+		return
+	}
+	defer stmt.Close()
+
+	kind, err := stmt.(*duckdb.Stmt).StatementType()
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get statement type")
+		return
+	}
+	if kind != duckdb.STATEMENT_TYPE_SELECT {
+		err = errors.Errorf("only select queries are allowed")
+	}
+	return
+}
@@ -0,0 +1,85 @@
+package duck
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// Rates of lines in view by field's value, in buckets of about a buckets'th
+// of the time they span
+func (dk *Duck) Rates(ctx context.Context, field string, buckets int) (rates []parcours.Rate, width time.Duration, err error) {
+
+	err = dk.refreshSurround(ctx)
+	if err != nil {
+		return
+	}
+
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	filter, _, surround := dk.viewed()
+	vw := sqlview.New(dialect{}, fields, surround)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+
+	var first, last sql.NullTime
+	query := fmt.Sprintf("SELECT MIN(logs.timestamp), MAX(logs.timestamp) FROM logs WHERE %s", cond)
+	err = dk.db.QueryRowContext(ctx, query, vw.Args...).Scan(&first, &last)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query time span")
+		return
+	}
+	if !first.Valid {
+		return
+	}
+	width = parcours.BucketWidth(last.Time.Sub(first.Time), buckets)
+
+	// buckets by microseconds since the epoch, the first line in each by time
+	vw = sqlview.New(dialect{}, fields, surround)
+	vw.Args = append(vw.Args, width.Microseconds())
+	value := vw.Value(field)
+	cond, err = vw.Lines(filter)
+	if err != nil {
+		return
+	}
+	query = fmt.Sprintf(`
+		SELECT epoch_us(logs.timestamp) // ? AS bucket, COALESCE(CAST(%s AS VARCHAR), '') AS value,
+			COUNT(*) AS count, arg_min(logs.id, (logs.timestamp, logs.id)) AS first
+		FROM logs WHERE %s AND logs.timestamp IS NOT NULL
+		GROUP BY bucket, value ORDER BY bucket, value`, value, cond)
+
+	rows, err := dk.db.QueryContext(ctx, query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to count lines over time")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, first int64
+		var rate parcours.Rate
+		err = rows.Scan(&bucket, &rate.Value, &rate.Count, &first)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan rate")
+			return
+		}
+		rate.Start = time.UnixMicro(bucket * width.Microseconds()).UTC()
+		rate.First = fmt.Sprint(first)
+		rates = append(rates, rate)
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating rates")
+	return
+}
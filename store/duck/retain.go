@@ -0,0 +1,95 @@
+package duck
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const evictInterval = time.Second
+
+// Retention limits the rows kept while following, zero values keeping all.
+type Retention struct {
+	// Rows keeps the last so many rows
+	Rows int
+	// Age keeps rows timestamped within so long of the newest
+	Age time.Duration
+}
+
+// Retain sets retention, applied in the background while following.
+func (dk *Duck) Retain(retention Retention) {
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+	dk.retention = retention
+}
+
+// unexported
+
+// retain evicts expired rows now and again, until ctx is done
+func (dk *Duck) retain(ctx context.Context) {
+
+	ticker := time.NewTicker(evictInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := dk.evict(ctx)
+		if err != nil && ctx.Err() == nil {
+			dk.logger.Error(ctx, "failed to evict expired rows", err)
+		}
+	}
+}
+
+// evict expired rows from both tables
+func (dk *Duck) evict(ctx context.Context) (err error) {
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+
+	var conds []string
+	var args []any
+	if dk.retention.Rows > 0 {
+		conds = append(conds, "id <= ?")
+		args = append(args, dk.lastID-int64(dk.retention.Rows))
+	}
+	if dk.retention.Age > 0 {
+		conds = append(conds, "timestamp < (SELECT MAX(timestamp) FROM logs) - to_microseconds(?)")
+		args = append(args, dk.retention.Age.Microseconds())
+	}
+	if len(conds) == 0 {
+		return
+	}
+
+	tx, err := dk.db.BeginTx(ctx, nil)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to begin eviction")
+		return
+	}
+	defer tx.Rollback()
+
+	expired := "SELECT id FROM logs WHERE " + strings.Join(conds, " OR ")
+	stmts := []string{
+		"DELETE FROM logs_raw WHERE id IN (" + expired + ")",
+		"DELETE FROM logs_pattern WHERE id IN (" + expired + ")",
+		"DELETE FROM logs WHERE id IN (" + expired + ")",
+	}
+	for _, stmt := range stmts {
+		_, err = tx.ExecContext(ctx, stmt, args...)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to delete expired rows")
+			return
+		}
+	}
+
+	err = tx.Commit()
+	err = errors.Wrapf(err, "failed to commit eviction")
+	return
+}
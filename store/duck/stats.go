@@ -0,0 +1,70 @@
+package duck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// Summarize field over lines in view with a numeric value for it, grouped
+// by the value of by unless empty, largest groups first
+func (dk *Duck) Summarize(ctx context.Context, field, by string, limit int) (stats []parcours.Stats, err error) {
+
+	err = dk.refreshSurround(ctx)
+	if err != nil {
+		return
+	}
+
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	// rendered in the order they appear, as they may take args
+	filter, _, surround := dk.viewed()
+	vw := sqlview.New(dialect{}, fields, surround)
+	group := "''"
+	if by != "" {
+		group = fmt.Sprintf("COALESCE(CAST(%s AS VARCHAR), '')", vw.Value(by))
+	}
+	value := vw.Value(field)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+
+	// cast through text, which raw fields are and typed ones all cast to
+	query := fmt.Sprintf(`
+		SELECT grp, COUNT(*) AS count, MIN(x), MAX(x), AVG(x),
+			quantile_cont(x, 0.5), quantile_cont(x, 0.9), quantile_cont(x, 0.99)
+		FROM (
+			SELECT %s AS grp, TRY_CAST(CAST(%s AS VARCHAR) AS DOUBLE) AS x
+			FROM logs WHERE %s
+		) WHERE x IS NOT NULL
+		GROUP BY grp ORDER BY count DESC, grp LIMIT %d`, group, value, cond, limit)
+
+	rows, err := dk.db.QueryContext(ctx, query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to summarize %s", field)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var st parcours.Stats
+		err = rows.Scan(&st.Group, &st.Count, &st.Min, &st.Max, &st.Mean, &st.P50, &st.P90, &st.P99)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan stats")
+			return
+		}
+		stats = append(stats, st)
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating stats")
+	return
+}
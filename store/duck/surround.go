@@ -0,0 +1,82 @@
+package duck
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// SetSurround for the view
+func (dk *Duck) SetSurround(ctx context.Context, surround parcours.Surround) (err error) {
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+
+	dk.surround = surround
+	if !surround.Active() {
+		return
+	}
+	err = dk.surroundLines(ctx)
+	return
+}
+
+// unexported
+
+// viewed filter and sorts, and whether lines are surrounded, as set
+func (dk *Duck) viewed() (filter parcours.Filter, sorts []parcours.Sort, surround bool) {
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+	return dk.filter, dk.viewSorts(), dk.surround.Active()
+}
+
+// viewSorts order the view, the stream's while surrounding. mu is expected
+// to be held.
+func (dk *Duck) viewSorts() []parcours.Sort {
+
+	if dk.surround.Active() {
+		return dk.surround.Sorts()
+	}
+	return dk.sorts
+}
+
+// refreshSurround takes lines loaded since into the surround table
+func (dk *Duck) refreshSurround(ctx context.Context) (err error) {
+
+	dk.mu.Lock()
+	defer dk.mu.Unlock()
+	if !dk.surround.Active() || dk.surrounded == dk.lastID {
+		return
+	}
+	err = dk.surroundLines(ctx)
+	return
+}
+
+// surroundLines takes matches of the filter and lines within reach of them
+// in the stream into the surround table, runs of adjacent lines sharing a
+// group. mu is expected to be held.
+func (dk *Duck) surroundLines(ctx context.Context) (err error) {
+
+	fields, err := dk.fields(ctx)
+	if err != nil {
+		return
+	}
+
+	vw := sqlview.New(dialect{}, fields, false)
+	query, err := vw.Surround(dk.filter, dk.surround)
+	if err != nil {
+		return
+	}
+
+	_, err = dk.db.ExecContext(ctx, "CREATE OR REPLACE TABLE surround AS "+query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to surround matches")
+		return
+	}
+
+	dk.surrounded = dk.lastID
+	return
+}
@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"parcours"
+)
+
+// Frequencies of a field's values among lines matching filter, most
+// frequent first
+func (mem *Memory) Frequencies(ctx context.Context, filter parcours.Filter, field string, limit int) (freqs []parcours.Frequency, total int, err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	patterns := map[string]*regexp.Regexp{}
+	counts := map[string]int{}
+	for i, r := range mem.rows {
+		if i%checkEvery == 0 {
+			err = ctx.Err()
+			if err != nil {
+				return
+			}
+		}
+
+		var match tri
+		match, err = matches(r, filter, patterns)
+		if err != nil {
+			return
+		}
+		if match != yes {
+			continue
+		}
+
+		total++
+		if val := value(r, field); val != nil {
+			counts[fmt.Sprint(val)]++
+		}
+	}
+
+	for val, count := range counts {
+		freqs = append(freqs, parcours.Frequency{Value: val, Count: count})
+	}
+	slices.SortFunc(freqs, func(a, b parcours.Frequency) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+	})
+	freqs = freqs[:min(len(freqs), limit)]
+	return
+}
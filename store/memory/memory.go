@@ -0,0 +1,454 @@
+// Package memory is a pure Go, in-memory store, for modest log files where
+// cgo and DuckDB are a bother.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+)
+
+type row struct {
+	id      int64
+	rec     parcours.Record
+	pattern int64
+}
+
+type Memory struct {
+	logger   parcours.Logger
+	decoder  *parcours.Decoder
+	tail     parcours.Broadcast
+	progress parcours.Tracker
+	miner    *parcours.Miner
+	origins  parcours.Origins
+
+	mu       sync.Mutex
+	rows     []row
+	promoted []string
+	filter   parcours.Filter
+	sorts    []parcours.Sort
+	surround parcours.Surround
+	view     []int
+	stale    bool
+	// per line in view while surrounding
+	matched []bool
+	groups  []int
+}
+
+// New creates a Memory store, decoding ndjson when dec is nil.
+func New(dec *parcours.Decoder, lgr parcours.Logger) (mem *Memory, err error) {
+
+	if dec == nil {
+		dec, err = parcours.NewDecoder(nil, nil)
+		if err != nil {
+			return
+		}
+	}
+
+	mem = &Memory{
+		logger:  lgr,
+		decoder: dec,
+		miner:   parcours.NewMiner(),
+		stale:   true,
+	}
+	return
+}
+
+// Load a file
+func (mem *Memory) Load(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	src, err := parcours.OpenSource(path, mem.decoder)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	defer mem.progress.Finish()
+
+	mem.progress.Start("loading", src.Size(), 0)
+	src.Track(&mem.progress)
+
+	err = src.Load(ctx, opts, true, mem.insert)
+	return
+}
+
+// Follow a file, loading what's there and then polling for more
+func (mem *Memory) Follow(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	src, err := parcours.OpenSource(path, mem.decoder)
+	if err != nil {
+		return
+	}
+
+	defer mem.progress.Finish()
+
+	mem.progress.Start("loading", src.Size(), 0)
+	src.Track(&mem.progress)
+
+	err = src.Load(ctx, opts, false, mem.insert)
+	src.Track(nil)
+	if err != nil {
+		src.Close()
+		return
+	}
+
+	go func() {
+		defer src.Close()
+		src.Follow(ctx, mem.logger, opts, mem.insert)
+	}()
+	return
+}
+
+// Promote a field
+func (mem *Memory) Promote(ctx context.Context, field string) (err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	if !slices.Contains(mem.promoted, field) && !isColumn(field) {
+		mem.promoted = append(mem.promoted, field)
+	}
+	return
+}
+
+// SetView Filter and Sort(s)
+func (mem *Memory) SetView(ctx context.Context, filter parcours.Filter, sorts []parcours.Sort) (err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	mem.filter = filter
+	mem.sorts = sorts
+	mem.stale = true
+	return
+}
+
+// SetSurround for the view
+func (mem *Memory) SetSurround(ctx context.Context, surround parcours.Surround) (err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	mem.surround = surround
+	mem.stale = true
+	return
+}
+
+// GetView fields and count
+func (mem *Memory) GetView(ctx context.Context) (fields []parcours.Field, count int, err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	err = mem.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	fields = mem.fields()
+	if mem.surround.Active() {
+		fields = parcours.SurroundFields(fields)
+	}
+	count = len(mem.view)
+	return
+}
+
+// GetPage of log lines
+func (mem *Memory) GetPage(ctx context.Context, offset, size int) (lines []parcours.Line, err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	err = mem.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	for i := offset; i < offset+size && i < len(mem.view); i++ {
+		lines = append(lines, mem.viewLine(i))
+	}
+	return
+}
+
+// GetPageAt returns lines after or before cursor, found by searching the
+// sorted view for its row
+func (mem *Memory) GetPageAt(ctx context.Context, cursor parcours.Cursor, size int) (lines []parcours.Line, err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	err = mem.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	start, end := 0, len(mem.view)
+	if cursor.ID != "" {
+		var num int64
+		num, err = strconv.ParseInt(cursor.ID, 10, 64)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to parse cursor id")
+			return
+		}
+
+		idx, ok := mem.index(num)
+		if !ok {
+			return
+		}
+		pos := mem.position(mem.rows[idx])
+		found := pos < len(mem.view) && mem.rows[mem.view[pos]].id == num
+
+		switch {
+		case cursor.Before && found && cursor.Inclusive:
+			end = pos + 1
+		case cursor.Before:
+			end = pos
+		case found && !cursor.Inclusive:
+			start = pos + 1
+		default:
+			start = pos
+		}
+	}
+
+	if cursor.Before {
+		start = max(end-size, 0)
+	} else {
+		end = min(start+size, end)
+	}
+
+	for i := start; i < end; i++ {
+		lines = append(lines, mem.viewLine(i))
+	}
+	return
+}
+
+// Locate the offset in the view of the line with id, or of its nearest
+// neighbour when it's filtered out
+func (mem *Memory) Locate(ctx context.Context, id string) (offset int, err error) {
+
+	num, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse id")
+		return
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	err = mem.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	idx, ok := mem.index(num)
+	if !ok {
+		return
+	}
+
+	offset = max(min(mem.position(mem.rows[idx]), len(mem.view)-1), 0)
+	return
+}
+
+// Source of the line with id
+func (mem *Memory) Source(ctx context.Context, id string) (path string, err error) {
+	return mem.origins.Path(id)
+}
+
+// GetJson returns raw json for a log line
+func (mem *Memory) GetJson(ctx context.Context, id string) (data map[string]any, err error) {
+
+	num, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse id")
+		return
+	}
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	idx, ok := mem.index(num)
+	if !ok {
+		err = errors.Errorf("no line with id %d", num)
+		return
+	}
+
+	// copy by way of json, as a store would hand over raw json
+	raw, err := json.Marshal(mem.rows[idx].rec.Data)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to marshal raw JSON")
+		return
+	}
+	data, err = parcours.UnmarshalRaw(raw)
+	return
+}
+
+// Progress of a load
+func (mem *Memory) Progress() parcours.Progress {
+	return mem.progress.Progress()
+}
+
+// Tail streams log lines as they are inserted, until ctx is done
+func (mem *Memory) Tail(ctx context.Context) (lines <-chan parcours.Line, err error) {
+
+	lines = mem.tail.Subscribe(ctx)
+	return
+}
+
+// unexported
+
+var columns = []parcours.Field{
+	{Name: "id", Type: "BIGINT"},
+	{Name: "timestamp", Type: "TIMESTAMP"},
+	{Name: "level", Type: "VARCHAR"},
+	{Name: "message", Type: "VARCHAR"},
+}
+
+func isColumn(field string) bool {
+	return slices.ContainsFunc(columns, func(col parcours.Field) bool { return col.Name == field })
+}
+
+func (mem *Memory) insert(ctx context.Context, path string, recs []parcours.Record) (err error) {
+
+	if len(recs) == 0 {
+		return
+	}
+
+	mem.mu.Lock()
+
+	var lastID int64
+	if len(mem.rows) > 0 {
+		lastID = mem.rows[len(mem.rows)-1].id
+	}
+
+	var lines []parcours.Line
+	publish := mem.tail.Active()
+	for i, rec := range recs {
+		r := row{id: lastID + int64(i) + 1, rec: rec, pattern: mem.miner.Add(rec.Message)}
+		mem.rows = append(mem.rows, r)
+		if publish {
+			lines = append(lines, mem.line(r))
+		}
+	}
+	mem.origins.Add(path, lastID+1, len(recs))
+	mem.stale = true
+
+	mem.mu.Unlock()
+
+	mem.tail.Publish(lines)
+	return
+}
+
+func (mem *Memory) fields() (fields []parcours.Field) {
+
+	fields = slices.Clone(columns)
+	for _, name := range mem.promoted {
+		fields = append(fields, parcours.Field{Name: name, Type: "VARCHAR"})
+	}
+	return
+}
+
+func (mem *Memory) line(r row) (line parcours.Line) {
+
+	line = make(parcours.Line, 0, len(columns)+len(mem.promoted))
+	for _, col := range columns {
+		line = append(line, parcours.Value{Raw: value(r, col.Name)})
+	}
+	for _, name := range mem.promoted {
+		line = append(line, parcours.Value{Raw: value(r, name)})
+	}
+	return
+}
+
+// viewLine is the line at offset i in view, with whether it matched and
+// its group while surrounding
+func (mem *Memory) viewLine(i int) (line parcours.Line) {
+
+	line = mem.line(mem.rows[mem.view[i]])
+	if mem.surround.Active() {
+		line = append(line, parcours.Value{Raw: mem.matched[i]}, parcours.Value{Raw: int64(mem.groups[i])})
+	}
+	return
+}
+
+// viewSorts order the view, the stream's while surrounding
+func (mem *Memory) viewSorts() []parcours.Sort {
+
+	if mem.surround.Active() {
+		return mem.surround.Sorts()
+	}
+	return mem.sorts
+}
+
+// index finds a row by id, rows being in id order
+func (mem *Memory) index(id int64) (idx int, ok bool) {
+
+	idx = sort.Search(len(mem.rows), func(i int) bool { return mem.rows[i].id >= id })
+	ok = idx < len(mem.rows) && mem.rows[idx].id == id
+	return
+}
+
+// position of a row in the sorted view, or where it would be when it's
+// filtered out
+func (mem *Memory) position(r row) int {
+
+	return sort.Search(len(mem.view), func(i int) bool {
+		return compareRows(mem.rows[mem.view[i]], r, mem.viewSorts()) >= 0
+	})
+}
+
+// value of a field, nil standing in for null
+func value(r row, field string) any {
+
+	switch field {
+	case "id":
+		return r.id
+	case "timestamp":
+		if r.rec.Timestamp.IsZero() {
+			return nil
+		}
+		return r.rec.Timestamp
+	case "level":
+		if r.rec.Level == "" {
+			return nil
+		}
+		return r.rec.Level
+	case "message":
+		return r.rec.Message
+	case parcours.PatternField:
+		return r.pattern
+	}
+
+	return extract(r.rec.Data[field])
+}
+
+// extract a raw value as a string, as json_extract_string would
+func extract(val any) any {
+
+	switch val := val.(type) {
+	case nil:
+		return nil
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	}
+
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return nil
+	}
+	return string(raw)
+}
@@ -0,0 +1,21 @@
+package memory
+
+import (
+	"testing"
+
+	"parcours"
+	"parcours/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+
+	storetest.Run(t, func(t *testing.T, dec *parcours.Decoder) parcours.Store {
+
+		mem, err := New(dec, storetest.Logger{T: t})
+		if err != nil {
+			t.Fatalf("failed to create memory store: %+v", err)
+		}
+
+		return mem
+	})
+}
@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"parcours"
+)
+
+// Patterns of lines in view, most frequent first
+func (mem *Memory) Patterns(ctx context.Context) (patterns []parcours.Pattern, err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	err = mem.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	counts := map[int64]int{}
+	for _, idx := range mem.view {
+		counts[mem.rows[idx].pattern]++
+	}
+
+	for id, count := range counts {
+		patterns = append(patterns, parcours.Pattern{ID: id, Template: mem.miner.Template(id), Count: count})
+	}
+	slices.SortFunc(patterns, func(a, b parcours.Pattern) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.ID, b.ID))
+	})
+	return
+}
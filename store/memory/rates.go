@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"parcours"
+)
+
+// Rates of lines in view by field's value, in buckets of about a buckets'th
+// of the time they span
+func (mem *Memory) Rates(ctx context.Context, field string, buckets int) (rates []parcours.Rate, width time.Duration, err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	err = mem.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	var first, last time.Time
+	for _, idx := range mem.view {
+		ts := mem.rows[idx].rec.Timestamp
+		if ts.IsZero() {
+			continue
+		}
+		if first.IsZero() || ts.Before(first) {
+			first = ts
+		}
+		if ts.After(last) {
+			last = ts
+		}
+	}
+	if first.IsZero() {
+		return
+	}
+	width = parcours.BucketWidth(last.Sub(first), buckets)
+
+	type key struct {
+		bucket int64
+		value  string
+	}
+	counts := map[key]*parcours.Rate{}
+	firsts := map[key]row{}
+	for _, idx := range mem.view {
+		r := mem.rows[idx]
+		ts := r.rec.Timestamp
+		if ts.IsZero() {
+			continue
+		}
+
+		k := key{bucket: floorDiv(ts.UnixMicro(), width.Microseconds())}
+		if val := value(r, field); val != nil {
+			k.value = fmt.Sprint(val)
+		}
+
+		rate, ok := counts[k]
+		if !ok {
+			rate = &parcours.Rate{Start: time.UnixMicro(k.bucket * width.Microseconds()).UTC(), Value: k.value}
+			counts[k] = rate
+		}
+		rate.Count++
+		// the first by time, then id
+		prev, ok := firsts[k]
+		if !ok || cmp.Or(ts.Compare(prev.rec.Timestamp), cmp.Compare(r.id, prev.id)) < 0 {
+			rate.First, firsts[k] = fmt.Sprint(r.id), r
+		}
+	}
+
+	for _, rate := range counts {
+		rates = append(rates, *rate)
+	}
+	slices.SortFunc(rates, func(a, b parcours.Rate) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.Value, b.Value))
+	})
+	return
+}
+
+// floorDiv divides rounding down, so that times before the epoch bucket alike
+func floorDiv(a, b int64) int64 {
+
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"parcours"
+)
+
+// Summarize field over lines in view with a numeric value for it, grouped
+// by the value of by unless empty, largest groups first
+func (mem *Memory) Summarize(ctx context.Context, field, by string, limit int) (stats []parcours.Stats, err error) {
+
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+
+	err = mem.refresh(ctx)
+	if err != nil {
+		return
+	}
+
+	groups := map[string][]float64{}
+	for _, idx := range mem.view {
+		r := mem.rows[idx]
+		val := value(r, field)
+		if val == nil {
+			continue
+		}
+		// as DuckDB casts text to a double
+		num, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(val)), 64)
+		if err != nil {
+			continue
+		}
+
+		group := ""
+		if by != "" {
+			if val := value(r, by); val != nil {
+				group = fmt.Sprint(val)
+			}
+		}
+		groups[group] = append(groups[group], num)
+	}
+
+	stats = parcours.SummarizeGroups(groups, limit)
+	return
+}
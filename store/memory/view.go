@@ -0,0 +1,311 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+)
+
+// checkEvery is how many rows to filter between checks for cancellation
+const checkEvery = 10000
+
+// tri is a three-valued logic result, following SQL, where comparisons
+// with null are unknown and unknown rows are filtered out.
+type tri int
+
+const (
+	no tri = iota
+	yes
+	unknown
+)
+
+func truth(ok bool) tri {
+	if ok {
+		return yes
+	}
+	return no
+}
+
+// refresh the filtered and sorted view when stale, checking ctx now and again
+// mu is expected to be held
+func (mem *Memory) refresh(ctx context.Context) (err error) {
+
+	err = ctx.Err()
+	if err != nil || !mem.stale {
+		return
+	}
+
+	patterns := map[string]*regexp.Regexp{}
+	view := []int{}
+	hits := make([]bool, len(mem.rows))
+	for i, r := range mem.rows {
+		if i%checkEvery == 0 {
+			err = ctx.Err()
+			if err != nil {
+				return
+			}
+		}
+
+		var match tri
+		match, err = matches(r, mem.filter, patterns)
+		if err != nil {
+			return
+		}
+		if match == yes {
+			view = append(view, i)
+			hits[i] = true
+		}
+	}
+
+	if mem.surround.Active() {
+		mem.view, mem.matched, mem.groups = surround(mem.rows, hits, mem.surround)
+		mem.stale = false
+		return
+	}
+
+	slices.SortStableFunc(view, func(a, b int) int {
+		return compareRows(mem.rows[a], mem.rows[b], mem.sorts)
+	})
+
+	mem.view = view
+	mem.stale = false
+	return
+}
+
+// surround takes hits and rows within lines of them in the stream ordered
+// by sur, numbering runs of adjacent rows as groups
+func surround(rows []row, hits []bool, sur parcours.Surround) (view []int, matched []bool, groups []int) {
+
+	sorts := sur.Sorts()
+	stream := make([]int, len(rows))
+	for i := range stream {
+		stream[i] = i
+	}
+	slices.SortStableFunc(stream, func(a, b int) int {
+		return compareRows(rows[a], rows[b], sorts)
+	})
+
+	// rows near a hit, counting the hits within reach of each position
+	reach := make([]int, len(stream)+1)
+	for pos, idx := range stream {
+		if hits[idx] {
+			reach[max(pos-sur.Lines, 0)]++
+			reach[min(pos+sur.Lines+1, len(stream))]--
+		}
+	}
+
+	group, last, near := 0, -1, 0
+	for pos, idx := range stream {
+		near += reach[pos]
+		if near == 0 {
+			continue
+		}
+		if pos != last+1 {
+			group++
+		}
+		last = pos
+
+		view = append(view, idx)
+		matched = append(matched, hits[idx])
+		groups = append(groups, group)
+	}
+	return
+}
+
+func matches(r row, filter parcours.Filter, patterns map[string]*regexp.Regexp) (match tri, err error) {
+
+	switch filter.Op {
+	case parcours.And, parcours.Or:
+		// And starts out true, Or false
+		match = truth(filter.Op == parcours.And)
+		for _, child := range filter.Children {
+			var sub tri
+			sub, err = matches(r, *child, patterns)
+			if err != nil {
+				return
+			}
+
+			switch {
+			case filter.Op == parcours.And && sub == no, filter.Op == parcours.Or && sub == yes:
+				match = sub
+				return
+			case sub == unknown:
+				match = unknown
+			}
+		}
+
+	case parcours.Not:
+		if len(filter.Children) != 1 {
+			err = errors.Errorf("not filter expects one child, got %d", len(filter.Children))
+			return
+		}
+		match, err = matches(r, *filter.Children[0], patterns)
+		if match != unknown {
+			match = truth(match == no)
+		}
+
+	default:
+		match, err = compareFilter(r, filter, patterns)
+	}
+	return
+}
+
+func compareFilter(r row, filter parcours.Filter, patterns map[string]*regexp.Regexp) (match tri, err error) {
+
+	if filter.Field == "" {
+		err = errors.Errorf("comparison filter expects a field")
+		return
+	}
+	val := value(r, filter.Field)
+
+	if filter.Value == nil {
+		switch filter.Op {
+		case parcours.Eq:
+			match = truth(val == nil)
+		case parcours.Ne:
+			match = truth(val != nil)
+		default:
+			err = errors.Errorf("filter on %s compares with nil", filter.Field)
+		}
+		return
+	}
+
+	if filter.Field == "level" && parcours.NormalizeLevel(filter.Value) == parcours.LevelUnknown {
+		err = errors.Errorf("unknown level: %v", filter.Value)
+		return
+	}
+	if val == nil {
+		match = unknown
+		return
+	}
+
+	switch filter.Op {
+	case parcours.Contains:
+		match = truth(strings.Contains(fmt.Sprint(val), fmt.Sprint(filter.Value)))
+		return
+	case parcours.Match:
+		pattern := fmt.Sprint(filter.Value)
+		re, ok := patterns[pattern]
+		if !ok {
+			re, err = regexp.Compile(pattern)
+			if err != nil {
+				err = errors.Wrapf(err, "failed to compile filter pattern")
+				return
+			}
+			patterns[pattern] = re
+		}
+		match = truth(re.MatchString(fmt.Sprint(val)))
+		return
+	}
+
+	cmp, err := compareValue(filter.Field, val, filter.Value)
+	if err != nil {
+		return
+	}
+
+	switch filter.Op {
+	case parcours.Eq:
+		match = truth(cmp == 0)
+	case parcours.Ne:
+		match = truth(cmp != 0)
+	case parcours.Gt:
+		match = truth(cmp > 0)
+	case parcours.Gte:
+		match = truth(cmp >= 0)
+	case parcours.Lt:
+		match = truth(cmp < 0)
+	case parcours.Lte:
+		match = truth(cmp <= 0)
+	default:
+		err = errors.Errorf("unknown filter op: %d", filter.Op)
+	}
+	return
+}
+
+// compareValue compares a row's value with a filter's, converting the latter
+// to suit the field: levels by severity, timestamps and ids as such, and
+// everything else as strings.
+func compareValue(field string, val, other any) (cmp int, err error) {
+
+	switch val := val.(type) {
+	case time.Time:
+		ts, ok := other.(time.Time)
+		if !ok {
+			ts = parcours.ParseTime(other, "")
+		}
+		if ts.IsZero() {
+			err = errors.Errorf("cannot compare %s with %v", field, other)
+			return
+		}
+		cmp = val.Compare(ts)
+		return
+
+	case int64:
+		var num int64
+		num, err = strconv.ParseInt(fmt.Sprint(other), 10, 64)
+		if err != nil {
+			err = errors.Wrapf(err, "cannot compare %s with %v", field, other)
+			return
+		}
+		cmp = compareInt(val, num)
+		return
+	}
+
+	if field == "level" {
+		cmp = compareInt(int64(parcours.NormalizeLevel(val)), int64(parcours.NormalizeLevel(other)))
+		return
+	}
+
+	if lvl, ok := other.(parcours.Level); ok {
+		other = lvl.String()
+	}
+	cmp = strings.Compare(fmt.Sprint(val), fmt.Sprint(other))
+	return
+}
+
+// compareRows orders by sorts, nulls last, with id breaking ties
+func compareRows(a, b row, sorts []parcours.Sort) int {
+
+	for _, sort := range sorts {
+		va, vb := value(a, sort.Field), value(b, sort.Field)
+
+		switch {
+		case va == nil && vb == nil:
+			continue
+		case va == nil:
+			return 1
+		case vb == nil:
+			return -1
+		}
+
+		cmp, err := compareValue(sort.Field, va, vb)
+		if err != nil || cmp == 0 {
+			continue
+		}
+		if sort.Desc {
+			cmp = -cmp
+		}
+		return cmp
+	}
+
+	return compareInt(a.id, b.id)
+}
+
+func compareInt(a, b int64) int {
+
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
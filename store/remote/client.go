@@ -0,0 +1,345 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+)
+
+const progressTimeout = time.Second
+
+// Client is a Store served by a remote Server.
+// Paths given to Load and Follow are those on the server's host, and
+// follows last as long as the server rather than ctx.
+type Client struct {
+	base   string
+	client *http.Client
+}
+
+// NewClient creates a Client for the server at base, using http.DefaultClient
+// when client is nil.
+func NewClient(base string, client *http.Client) *Client {
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Client{
+		base:   strings.TrimSuffix(base, "/"),
+		client: client,
+	}
+}
+
+// Load a file
+func (cl *Client) Load(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	err = cl.call(ctx, http.MethodPost, "/load", newLoadRequest(path, opts), nil)
+	return
+}
+
+// Follow a file
+func (cl *Client) Follow(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	err = cl.call(ctx, http.MethodPost, "/follow", newLoadRequest(path, opts), nil)
+	return
+}
+
+// Promote a field
+func (cl *Client) Promote(ctx context.Context, field string) (err error) {
+
+	err = cl.call(ctx, http.MethodPost, "/promote", promoteRequest{Field: field}, nil)
+	return
+}
+
+// SetView Filter and Sort(s)
+func (cl *Client) SetView(ctx context.Context, filter parcours.Filter, sorts []parcours.Sort) (err error) {
+
+	body := viewRequest{Filter: wireFilter(filter), Sorts: sorts}
+	err = cl.call(ctx, http.MethodPut, "/view", body, nil)
+	return
+}
+
+// GetView fields and count
+func (cl *Client) GetView(ctx context.Context) (fields []parcours.Field, count int, err error) {
+
+	var body viewResponse
+	err = cl.call(ctx, http.MethodGet, "/view", nil, &body)
+	if err != nil {
+		return
+	}
+
+	fields, count = body.Fields, body.Count
+	return
+}
+
+// SetSurround for the view, the server's store being a Surrounder
+func (cl *Client) SetSurround(ctx context.Context, surround parcours.Surround) (err error) {
+
+	body := surroundRequest{Lines: surround.Lines, By: surround.By}
+	err = cl.call(ctx, http.MethodPut, "/surround", body, nil)
+	return
+}
+
+// GetPage of log lines
+func (cl *Client) GetPage(ctx context.Context, offset, size int) (lines []parcours.Line, err error) {
+
+	query := url.Values{}
+	query.Set("offset", fmt.Sprint(offset))
+	query.Set("size", fmt.Sprint(size))
+
+	lines, err = cl.lines(ctx, "/page?"+query.Encode())
+	return
+}
+
+// GetPageAt returns lines after or before cursor, the server's store
+// being a Seeker
+func (cl *Client) GetPageAt(ctx context.Context, cursor parcours.Cursor, size int) (lines []parcours.Line, err error) {
+
+	query := url.Values{}
+	query.Set("cursor", cursor.ID)
+	query.Set("before", fmt.Sprint(cursor.Before))
+	query.Set("inclusive", fmt.Sprint(cursor.Inclusive))
+	query.Set("size", fmt.Sprint(size))
+
+	lines, err = cl.lines(ctx, "/seek?"+query.Encode())
+	return
+}
+
+// Locate the offset in the view of the line with id, or of its nearest
+// neighbour when it's filtered out
+func (cl *Client) Locate(ctx context.Context, id string) (offset int, err error) {
+
+	var body locateResponse
+	err = cl.call(ctx, http.MethodGet, "/locate/"+url.PathEscape(id), nil, &body)
+	offset = body.Offset
+	return
+}
+
+// GetJson returns raw json for a log line
+func (cl *Client) GetJson(ctx context.Context, id string) (data map[string]any, err error) {
+
+	var raw json.RawMessage
+	err = cl.call(ctx, http.MethodGet, "/json/"+url.PathEscape(id), nil, &raw)
+	if err != nil {
+		return
+	}
+
+	data, err = parcours.UnmarshalRaw(raw)
+	return
+}
+
+// Source of the line with id, the server's store being a Sourcer
+func (cl *Client) Source(ctx context.Context, id string) (path string, err error) {
+
+	var body sourceResponse
+	err = cl.call(ctx, http.MethodGet, "/source/"+url.PathEscape(id), nil, &body)
+	path = body.Path
+	return
+}
+
+// Query runs read-only SQL, the server's store being a Querier
+func (cl *Client) Query(ctx context.Context, query string) (fields []parcours.Field, lines []parcours.Line, err error) {
+
+	var body queryResponse
+	err = cl.call(ctx, http.MethodPost, "/query", queryRequest{Query: query}, &body)
+	if err != nil {
+		return
+	}
+
+	fields = body.Fields
+	for _, ln := range body.Lines {
+		lines = append(lines, decodeLine(ln))
+	}
+	return
+}
+
+// Frequencies of a field's values among lines matching filter, the
+// server's store being a Counter
+func (cl *Client) Frequencies(ctx context.Context, filter parcours.Filter, field string, limit int) (freqs []parcours.Frequency, total int, err error) {
+
+	var body frequenciesResponse
+	request := frequenciesRequest{Filter: wireFilter(filter), Field: field, Limit: limit}
+	err = cl.call(ctx, http.MethodPost, "/frequencies", request, &body)
+	freqs, total = body.Frequencies, body.Total
+	return
+}
+
+// Patterns of lines in view, the server's store being a Patterner
+func (cl *Client) Patterns(ctx context.Context) (patterns []parcours.Pattern, err error) {
+
+	err = cl.call(ctx, http.MethodGet, "/patterns", nil, &patterns)
+	return
+}
+
+// Rates of lines in view by field's value over time, the server's store
+// being a Rater
+func (cl *Client) Rates(ctx context.Context, field string, buckets int) (rates []parcours.Rate, width time.Duration, err error) {
+
+	query := url.Values{}
+	query.Set("field", field)
+	query.Set("buckets", fmt.Sprint(buckets))
+
+	var body ratesResponse
+	err = cl.call(ctx, http.MethodGet, "/rates?"+query.Encode(), nil, &body)
+	rates, width = body.Rates, body.Width
+	return
+}
+
+// Summarize field over lines in view, the server's store being a
+// Summarizer
+func (cl *Client) Summarize(ctx context.Context, field, by string, limit int) (stats []parcours.Stats, err error) {
+
+	query := url.Values{}
+	query.Set("field", field)
+	query.Set("by", by)
+	query.Set("limit", fmt.Sprint(limit))
+
+	err = cl.call(ctx, http.MethodGet, "/stats?"+query.Encode(), nil, &stats)
+	return
+}
+
+// Progress of the remote store, idle when it can't be had
+func (cl *Client) Progress() (progress parcours.Progress) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), progressTimeout)
+	defer cancel()
+
+	err := cl.call(ctx, http.MethodGet, "/progress", nil, &progress)
+	if err != nil {
+		return parcours.Progress{}
+	}
+	return
+}
+
+// Tail streams log lines until ctx is done
+func (cl *Client) Tail(ctx context.Context) (lines <-chan parcours.Line, err error) {
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, cl.base+"/tail", nil)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to create tail request")
+		return
+	}
+	request.Header.Set("Accept", "text/event-stream")
+
+	response, err := cl.client.Do(request)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to request tail")
+		return
+	}
+	if response.StatusCode != http.StatusOK {
+		err = responseError(response)
+		response.Body.Close()
+		return
+	}
+
+	stream := make(chan parcours.Line)
+	go func() {
+		defer close(stream)
+		defer response.Body.Close()
+
+		reader := bufio.NewReader(response.Body)
+		for {
+			text, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			data, ok := strings.CutPrefix(strings.TrimRight(text, "\r\n"), "data: ")
+			if !ok {
+				continue
+			}
+
+			var ln line
+			if json.Unmarshal([]byte(data), &ln) != nil {
+				continue
+			}
+
+			select {
+			case stream <- decodeLine(ln):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	lines = stream
+	return
+}
+
+// unexported
+
+func (cl *Client) call(ctx context.Context, method, path string, in, out any) (err error) {
+
+	var body io.Reader
+	if in != nil {
+		var data []byte
+		data, err = json.Marshal(in)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to encode request body")
+			return
+		}
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, cl.base+path, body)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to create request")
+		return
+	}
+	if in != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := cl.client.Do(request)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to %s %s", method, path)
+		return
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		err = responseError(response)
+		return
+	}
+	if out == nil {
+		return
+	}
+
+	err = json.NewDecoder(response.Body).Decode(out)
+	err = errors.Wrapf(err, "failed to decode response body")
+	return
+}
+
+func (cl *Client) lines(ctx context.Context, path string) (lines []parcours.Line, err error) {
+
+	var body []line
+	err = cl.call(ctx, http.MethodGet, path, nil, &body)
+	if err != nil {
+		return
+	}
+
+	for _, ln := range body {
+		lines = append(lines, decodeLine(ln))
+	}
+	return
+}
+
+func responseError(response *http.Response) error {
+
+	var body errorResponse
+	if json.NewDecoder(response.Body).Decode(&body) != nil || body.Error == "" {
+		return errors.Errorf("remote responded %s", response.Status)
+	}
+	return errors.Errorf("remote: %s", body.Error)
+}
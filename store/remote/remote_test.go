@@ -0,0 +1,70 @@
+package remote
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"parcours"
+	"parcours/store/memory"
+	"parcours/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+
+	storetest.Run(t, func(t *testing.T, dec *parcours.Decoder) parcours.Store {
+
+		mem, err := memory.New(dec, storetest.Logger{T: t})
+		if err != nil {
+			t.Fatalf("failed to create memory store: %+v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		// fixtures, and the copies tests follow
+		opts := ServerOptions{Paths: []string{storetest.Fixture(""), os.TempDir()}}
+		svr := httptest.NewServer(NewServer(ctx, mem, storetest.Logger{T: t}, opts))
+		t.Cleanup(svr.Close)
+		t.Cleanup(cancel)
+
+		return NewClient(svr.URL, svr.Client())
+	})
+}
+
+func TestServerRefuses(t *testing.T) {
+
+	dec, err := parcours.NewDecoder(nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %+v", err)
+	}
+	mem, err := memory.New(dec, storetest.Logger{T: t})
+	if err != nil {
+		t.Fatalf("failed to create memory store: %+v", err)
+	}
+
+	opts := ServerOptions{Paths: []string{storetest.Fixture("smar.log")}}
+	svr := httptest.NewServer(NewServer(t.Context(), mem, storetest.Logger{T: t}, opts))
+	t.Cleanup(svr.Close)
+	cl := NewClient(svr.URL, svr.Client())
+
+	err = cl.Load(t.Context(), storetest.Fixture("smar.log"), parcours.LoadOptions{})
+	if err != nil {
+		t.Errorf("expected allowed path to load, got %v", err)
+	}
+
+	for _, path := range []string{storetest.Fixture("levels.log"), storetest.Fixture("smar.log/../levels.log"), "/etc/hostname"} {
+		err = cl.Load(t.Context(), path, parcours.LoadOptions{})
+		if err == nil {
+			t.Errorf("expected %s to be refused", path)
+		}
+	}
+
+	err = cl.Promote(t.Context(), `x VARCHAR; COPY (SELECT 42) TO '/tmp/pwn.csv'; --`)
+	if err == nil {
+		t.Errorf("expected invalid field to be refused")
+	}
+	err = cl.SetView(t.Context(), parcours.Filter{Op: parcours.Eq, Field: "a b", Value: "x"}, nil)
+	if err == nil {
+		t.Errorf("expected invalid filter field to be refused")
+	}
+}
@@ -0,0 +1,509 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+)
+
+// ServerOptions limit what clients of a Server may do.
+type ServerOptions struct {
+	// Paths of files clients may load or follow, or of directories holding
+	// them, none when empty
+	Paths []string
+	// Query lets clients run SQL, reading anything the store can
+	Query bool
+}
+
+// Server serves a Store over http.
+type Server struct {
+	ctx    context.Context
+	store  parcours.Store
+	logger parcours.Logger
+	opts   ServerOptions
+	mux    *http.ServeMux
+}
+
+// NewServer creates a Server for store, follows lasting until ctx is done.
+func NewServer(ctx context.Context, store parcours.Store, lgr parcours.Logger, opts ServerOptions) (svr *Server) {
+
+	svr = &Server{
+		ctx:    ctx,
+		store:  store,
+		logger: lgr,
+		mux:    http.NewServeMux(),
+	}
+
+	svr.opts = opts
+	svr.opts.Paths = make([]string, len(opts.Paths))
+	for i, path := range opts.Paths {
+		svr.opts.Paths[i] = resolvePath(path)
+	}
+
+	svr.mux.HandleFunc("POST /load", svr.load)
+	svr.mux.HandleFunc("POST /follow", svr.follow)
+	svr.mux.HandleFunc("POST /promote", svr.promote)
+	svr.mux.HandleFunc("PUT /view", svr.setView)
+	svr.mux.HandleFunc("GET /view", svr.getView)
+	svr.mux.HandleFunc("PUT /surround", svr.setSurround)
+	svr.mux.HandleFunc("GET /page", svr.getPage)
+	svr.mux.HandleFunc("GET /seek", svr.getPageAt)
+	svr.mux.HandleFunc("GET /locate/{id}", svr.locate)
+	svr.mux.HandleFunc("GET /json/{id}", svr.getJson)
+	svr.mux.HandleFunc("GET /source/{id}", svr.source)
+	svr.mux.HandleFunc("POST /query", svr.query)
+	svr.mux.HandleFunc("POST /frequencies", svr.frequencies)
+	svr.mux.HandleFunc("GET /patterns", svr.patterns)
+	svr.mux.HandleFunc("GET /rates", svr.rates)
+	svr.mux.HandleFunc("GET /stats", svr.stats)
+	svr.mux.HandleFunc("GET /tail", svr.tail)
+	svr.mux.HandleFunc("GET /progress", svr.progress)
+
+	return
+}
+
+func (svr *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	svr.mux.ServeHTTP(writer, request)
+}
+
+// unexported
+
+// errForbidden marks requests refused by the server's options
+var errForbidden = errors.New("forbidden")
+
+// fieldName that may be sent by clients, as found in log records
+var fieldName = regexp.MustCompile(`^[\w.@-]{1,128}$`)
+
+func (svr *Server) load(writer http.ResponseWriter, request *http.Request) {
+
+	var body loadRequest
+	err := decodeBody(request, &body)
+	if err == nil {
+		err = svr.allowPath(body.Path)
+	}
+	if err == nil {
+		err = svr.store.Load(request.Context(), body.Path, body.options())
+	}
+	svr.respond(writer, request, nil, err)
+}
+
+func (svr *Server) follow(writer http.ResponseWriter, request *http.Request) {
+
+	var body loadRequest
+	err := decodeBody(request, &body)
+	if err == nil {
+		err = svr.allowPath(body.Path)
+	}
+	if err == nil {
+		err = svr.store.Follow(svr.ctx, body.Path, body.options())
+	}
+	svr.respond(writer, request, nil, err)
+}
+
+func (svr *Server) promote(writer http.ResponseWriter, request *http.Request) {
+
+	var body promoteRequest
+	err := decodeBody(request, &body)
+	if err == nil {
+		err = checkFields(body.Field)
+	}
+	if err == nil {
+		err = svr.store.Promote(request.Context(), body.Field)
+	}
+	svr.respond(writer, request, nil, err)
+}
+
+func (svr *Server) setView(writer http.ResponseWriter, request *http.Request) {
+
+	var body viewRequest
+	err := decodeBody(request, &body)
+	if err == nil {
+		err = checkFilter(body.Filter, body.Sorts)
+	}
+	if err == nil {
+		localFilter(&body.Filter)
+		err = svr.store.SetView(request.Context(), body.Filter, body.Sorts)
+	}
+	svr.respond(writer, request, nil, err)
+}
+
+func (svr *Server) getView(writer http.ResponseWriter, request *http.Request) {
+
+	var body viewResponse
+	var err error
+
+	body.Fields, body.Count, err = svr.store.GetView(request.Context())
+	svr.respond(writer, request, body, err)
+}
+
+func (svr *Server) setSurround(writer http.ResponseWriter, request *http.Request) {
+
+	surrounder, ok := svr.store.(parcours.Surrounder)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not surround matches"))
+		return
+	}
+
+	var body surroundRequest
+	err := decodeBody(request, &body)
+	if err == nil {
+		err = checkFields(body.By)
+	}
+	if err == nil {
+		err = surrounder.SetSurround(request.Context(), parcours.Surround{Lines: body.Lines, By: body.By})
+	}
+	svr.respond(writer, request, nil, err)
+}
+
+func (svr *Server) getPage(writer http.ResponseWriter, request *http.Request) {
+
+	query := request.URL.Query()
+	offset, err := strconv.Atoi(query.Get("offset"))
+	if err != nil {
+		svr.respond(writer, request, nil, errors.Wrapf(err, "failed to parse offset"))
+		return
+	}
+	size, err := strconv.Atoi(query.Get("size"))
+	if err != nil {
+		svr.respond(writer, request, nil, errors.Wrapf(err, "failed to parse size"))
+		return
+	}
+
+	lines, err := svr.store.GetPage(request.Context(), offset, size)
+	svr.respondLines(writer, request, lines, err)
+}
+
+func (svr *Server) getPageAt(writer http.ResponseWriter, request *http.Request) {
+
+	seeker, ok := svr.store.(parcours.Seeker)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not page by cursor"))
+		return
+	}
+
+	query := request.URL.Query()
+	size, err := strconv.Atoi(query.Get("size"))
+	if err != nil {
+		svr.respond(writer, request, nil, errors.Wrapf(err, "failed to parse size"))
+		return
+	}
+	cursor := parcours.Cursor{
+		ID:        query.Get("cursor"),
+		Before:    query.Get("before") == "true",
+		Inclusive: query.Get("inclusive") == "true",
+	}
+
+	lines, err := seeker.GetPageAt(request.Context(), cursor, size)
+	svr.respondLines(writer, request, lines, err)
+}
+
+func (svr *Server) locate(writer http.ResponseWriter, request *http.Request) {
+
+	var body locateResponse
+	var err error
+
+	body.Offset, err = svr.store.Locate(request.Context(), request.PathValue("id"))
+	svr.respond(writer, request, body, err)
+}
+
+func (svr *Server) getJson(writer http.ResponseWriter, request *http.Request) {
+
+	data, err := svr.store.GetJson(request.Context(), request.PathValue("id"))
+	svr.respond(writer, request, data, err)
+}
+
+func (svr *Server) source(writer http.ResponseWriter, request *http.Request) {
+
+	sourcer, ok := svr.store.(parcours.Sourcer)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not know sources of lines"))
+		return
+	}
+
+	var body sourceResponse
+	var err error
+
+	body.Path, err = sourcer.Source(request.Context(), request.PathValue("id"))
+	svr.respond(writer, request, body, err)
+}
+
+func (svr *Server) query(writer http.ResponseWriter, request *http.Request) {
+
+	querier, ok := svr.store.(parcours.Querier)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not run queries"))
+		return
+	}
+
+	if !svr.opts.Query {
+		svr.respond(writer, request, nil, errors.Wrapf(errForbidden, "queries are not served"))
+		return
+	}
+
+	var body queryRequest
+	err := decodeBody(request, &body)
+	if err != nil {
+		svr.respond(writer, request, nil, err)
+		return
+	}
+
+	fields, lines, err := querier.Query(request.Context(), body.Query)
+
+	response := queryResponse{Fields: fields, Lines: make([]line, len(lines))}
+	for i, ln := range lines {
+		response.Lines[i] = encodeLine(ln)
+	}
+	svr.respond(writer, request, response, err)
+}
+
+func (svr *Server) frequencies(writer http.ResponseWriter, request *http.Request) {
+
+	counter, ok := svr.store.(parcours.Counter)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not count values"))
+		return
+	}
+
+	var body frequenciesRequest
+	err := decodeBody(request, &body)
+	if err == nil {
+		err = checkFields(body.Field)
+	}
+	if err == nil {
+		err = checkFilter(body.Filter, nil)
+	}
+	if err != nil {
+		svr.respond(writer, request, nil, err)
+		return
+	}
+	localFilter(&body.Filter)
+
+	var response frequenciesResponse
+	response.Frequencies, response.Total, err = counter.Frequencies(request.Context(), body.Filter, body.Field, body.Limit)
+	svr.respond(writer, request, response, err)
+}
+
+func (svr *Server) patterns(writer http.ResponseWriter, request *http.Request) {
+
+	patterner, ok := svr.store.(parcours.Patterner)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not mine patterns"))
+		return
+	}
+
+	patterns, err := patterner.Patterns(request.Context())
+	svr.respond(writer, request, patterns, err)
+}
+
+func (svr *Server) rates(writer http.ResponseWriter, request *http.Request) {
+
+	rater, ok := svr.store.(parcours.Rater)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not count lines over time"))
+		return
+	}
+
+	query := request.URL.Query()
+	buckets, err := strconv.Atoi(query.Get("buckets"))
+	if err != nil {
+		svr.respond(writer, request, nil, errors.Wrapf(err, "failed to parse buckets"))
+		return
+	}
+	err = checkFields(query.Get("field"))
+	if err != nil {
+		svr.respond(writer, request, nil, err)
+		return
+	}
+
+	var body ratesResponse
+	body.Rates, body.Width, err = rater.Rates(request.Context(), query.Get("field"), buckets)
+	svr.respond(writer, request, body, err)
+}
+
+func (svr *Server) stats(writer http.ResponseWriter, request *http.Request) {
+
+	summarizer, ok := svr.store.(parcours.Summarizer)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("store does not summarize fields"))
+		return
+	}
+
+	query := request.URL.Query()
+	limit, err := strconv.Atoi(query.Get("limit"))
+	if err != nil {
+		svr.respond(writer, request, nil, errors.Wrapf(err, "failed to parse limit"))
+		return
+	}
+	err = checkFields(query.Get("field"), query.Get("by"))
+	if err != nil {
+		svr.respond(writer, request, nil, err)
+		return
+	}
+
+	stats, err := summarizer.Summarize(request.Context(), query.Get("field"), query.Get("by"), limit)
+	svr.respond(writer, request, stats, err)
+}
+
+// progress of the store, idle when it doesn't report it
+func (svr *Server) progress(writer http.ResponseWriter, request *http.Request) {
+
+	var body parcours.Progress
+	if reporter, ok := svr.store.(parcours.Reporter); ok {
+		body = reporter.Progress()
+	}
+	svr.respond(writer, request, body, nil)
+}
+
+// tail streams lines as server-sent events until the client goes away
+func (svr *Server) tail(writer http.ResponseWriter, request *http.Request) {
+
+	ctx := request.Context()
+
+	lines, err := svr.store.Tail(ctx)
+	if err != nil {
+		svr.respond(writer, request, nil, err)
+		return
+	}
+
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		svr.respond(writer, request, nil, errors.Errorf("streaming not supported"))
+		return
+	}
+
+	// subscribed, so let the client know it won't miss anything from here
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for ln := range lines {
+		data, err := json.Marshal(encodeLine(ln))
+		if err != nil {
+			svr.logger.Error(ctx, "failed to encode tailed line", err)
+			continue
+		}
+
+		_, err = fmt.Fprintf(writer, "data: %s\n\n", data)
+		if err != nil {
+			// client's gone, lines closes once ctx is done
+			continue
+		}
+		flusher.Flush()
+	}
+}
+
+func (svr *Server) respond(writer http.ResponseWriter, request *http.Request, body any, err error) {
+
+	status := http.StatusOK
+	if err != nil {
+		svr.logger.Error(request.Context(), "request failed", err, "method", request.Method, "path", request.URL.Path)
+		status = http.StatusInternalServerError
+		if errors.Is(err, errForbidden) {
+			status = http.StatusForbidden
+		}
+		body = errorResponse{Error: err.Error()}
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if body == nil {
+		body = struct{}{}
+	}
+
+	err = json.NewEncoder(writer).Encode(body)
+	if err != nil {
+		svr.logger.Error(request.Context(), "failed to encode response", err)
+	}
+}
+
+func (svr *Server) respondLines(writer http.ResponseWriter, request *http.Request, lines []parcours.Line, err error) {
+
+	body := make([]line, len(lines))
+	for i, ln := range lines {
+		body[i] = encodeLine(ln)
+	}
+	svr.respond(writer, request, body, err)
+}
+
+func decodeBody(request *http.Request, body any) (err error) {
+
+	decoder := json.NewDecoder(request.Body)
+	decoder.UseNumber()
+
+	err = decoder.Decode(body)
+	err = errors.Wrapf(err, "failed to decode request body")
+	return
+}
+
+// allowPath errors unless path is one of the allowed files, or within one
+// of the allowed directories
+func (svr *Server) allowPath(path string) (err error) {
+
+	path = resolvePath(path)
+	for _, allowed := range svr.opts.Paths {
+		rel, relErr := filepath.Rel(allowed, path)
+		if relErr == nil && rel != ".." && !filepath.IsAbs(rel) && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return
+		}
+	}
+
+	err = errors.Wrapf(errForbidden, "path %s is not served", path)
+	return
+}
+
+// resolvePath to an absolute path without links, as far as it exists
+func resolvePath(path string) string {
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return abs
+	}
+	return resolved
+}
+
+// checkFields errors for any name that isn't a plausible field, empty
+// names being left to the store
+func checkFields(names ...string) (err error) {
+
+	for _, name := range names {
+		if name != "" && !fieldName.MatchString(name) {
+			err = errors.Errorf("invalid field name %q", name)
+			return
+		}
+	}
+	return
+}
+
+// checkFilter errors for fields of filter or sorts that checkFields doesn't pass
+func checkFilter(filter parcours.Filter, sorts []parcours.Sort) (err error) {
+
+	for _, sort := range sorts {
+		err = checkFields(sort.Field)
+		if err != nil {
+			return
+		}
+	}
+
+	err = checkFields(filter.Field)
+	for _, child := range filter.Children {
+		if err != nil {
+			return
+		}
+		err = checkFilter(*child, nil)
+	}
+	return
+}
@@ -0,0 +1,209 @@
+// Package remote exposes a Store over a small HTTP/JSON api with Server,
+// and implements Store against one with Client, so that logs can stay put
+// on a host while the TUI runs elsewhere.
+//
+// Routes:
+//
+//	POST /load     {"path", "last", "since", "until"}
+//	POST /follow   {"path", "last", "since", "until"}
+//	POST /promote  {"field"}
+//	PUT  /view     {"filter", "sorts"}
+//	GET  /view     {"fields", "count"}
+//	PUT  /surround {"lines", "by"}, for a Surrounder
+//	GET  /page     ?offset=&size= lines
+//	GET  /seek     ?cursor=&before=&inclusive=&size= lines, for a Seeker
+//	GET  /locate/:id {"offset"}
+//	GET  /json/:id raw record
+//	GET  /source/:id {"path"}, for a Sourcer
+//	POST /query    {"query"} {"fields", "lines"}, for a Querier
+//	POST /frequencies {"filter", "field", "limit"} {"frequencies", "total"}, for a Counter
+//	GET  /patterns [{"id", "template", "count"}], for a Patterner
+//	GET  /rates    ?field=&buckets= {"rates", "width"}, for a Rater
+//	GET  /stats    ?field=&by=&limit= [{"group", "count", "min", ...}], for a Summarizer
+//	GET  /tail     lines as server-sent events
+//	GET  /progress {"Phase", "Bytes", ...}
+//
+// Errors are reported with a non-2xx status and {"error"}. Paths outside
+// those the Server allows, and queries unless it allows them, are refused
+// with 403, and field names other than word characters, '.', '@' and '-'
+// are refused.
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"parcours"
+)
+
+type loadRequest struct {
+	Path  string    `json:"path"`
+	Last  int       `json:"last"`
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+}
+
+func newLoadRequest(path string, opts parcours.LoadOptions) loadRequest {
+	return loadRequest{Path: path, Last: opts.Last, Since: opts.Since, Until: opts.Until}
+}
+
+func (body loadRequest) options() parcours.LoadOptions {
+	return parcours.LoadOptions{Last: body.Last, Since: body.Since, Until: body.Until}
+}
+
+type promoteRequest struct {
+	Field string `json:"field"`
+}
+
+type viewRequest struct {
+	Filter parcours.Filter `json:"filter"`
+	Sorts  []parcours.Sort `json:"sorts"`
+}
+
+type surroundRequest struct {
+	Lines int    `json:"lines"`
+	By    string `json:"by"`
+}
+
+type viewResponse struct {
+	Fields []parcours.Field `json:"fields"`
+	Count  int              `json:"count"`
+}
+
+type locateResponse struct {
+	Offset int `json:"offset"`
+}
+
+type sourceResponse struct {
+	Path string `json:"path"`
+}
+
+type queryRequest struct {
+	Query string `json:"query"`
+}
+
+type queryResponse struct {
+	Fields []parcours.Field `json:"fields"`
+	Lines  []line           `json:"lines"`
+}
+
+type frequenciesRequest struct {
+	Filter parcours.Filter `json:"filter"`
+	Field  string          `json:"field"`
+	Limit  int             `json:"limit"`
+}
+
+type frequenciesResponse struct {
+	Frequencies []parcours.Frequency `json:"frequencies"`
+	Total       int                  `json:"total"`
+}
+
+type ratesResponse struct {
+	Rates []parcours.Rate `json:"rates"`
+	Width time.Duration   `json:"width"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// value carries the type of a line's value across the wire, null having
+// none of them set.
+type value struct {
+	Time   *time.Time `json:"t,omitempty"`
+	Int    *int64     `json:"i,omitempty"`
+	Float  *float64   `json:"f,omitempty"`
+	Bool   *bool      `json:"b,omitempty"`
+	String *string    `json:"s,omitempty"`
+}
+
+type line []value
+
+func encodeLine(ln parcours.Line) (enc line) {
+
+	enc = make(line, len(ln))
+	for i, val := range ln {
+		switch raw := val.Raw.(type) {
+		case nil:
+		case time.Time:
+			enc[i].Time = &raw
+		case int64:
+			enc[i].Int = &raw
+		case int32:
+			num := int64(raw)
+			enc[i].Int = &num
+		case int:
+			num := int64(raw)
+			enc[i].Int = &num
+		case float64:
+			enc[i].Float = &raw
+		case bool:
+			enc[i].Bool = &raw
+		case string:
+			enc[i].String = &raw
+		default:
+			str := fmt.Sprint(raw)
+			enc[i].String = &str
+		}
+	}
+	return
+}
+
+func decodeLine(enc line) (ln parcours.Line) {
+
+	ln = make(parcours.Line, len(enc))
+	for i, val := range enc {
+		switch {
+		case val.Time != nil:
+			ln[i].Raw = *val.Time
+		case val.Int != nil:
+			ln[i].Raw = *val.Int
+		case val.Float != nil:
+			ln[i].Raw = *val.Float
+		case val.Bool != nil:
+			ln[i].Raw = *val.Bool
+		case val.String != nil:
+			ln[i].Raw = *val.String
+		}
+	}
+	return
+}
+
+// wireFilter copies a filter with values that survive json: levels by name
+// and times as text.
+func wireFilter(filter parcours.Filter) parcours.Filter {
+
+	switch val := filter.Value.(type) {
+	case parcours.Level:
+		filter.Value = val.String()
+	case time.Time:
+		filter.Value = val.Format(time.RFC3339Nano)
+	}
+
+	children := make([]*parcours.Filter, len(filter.Children))
+	for i, child := range filter.Children {
+		copied := wireFilter(*child)
+		children[i] = &copied
+	}
+	filter.Children = children
+	return filter
+}
+
+// localFilter converts numbers decoded from the wire to int64 or float64.
+func localFilter(filter *parcours.Filter) {
+
+	if num, ok := filter.Value.(json.Number); ok {
+		if i, err := num.Int64(); err == nil {
+			filter.Value = i
+		} else if f, err := num.Float64(); err == nil {
+			filter.Value = f
+		} else {
+			filter.Value = num.String()
+		}
+	}
+
+	for _, child := range filter.Children {
+		localFilter(child)
+	}
+}
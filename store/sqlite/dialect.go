@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	msqlite "modernc.org/sqlite"
+
+	"parcours"
+)
+
+// dialect of SQLite for rendering views, with levels compared by their rank
+// and timestamps as text
+type dialect struct{}
+
+func (dialect) Same() string {
+	return "IS"
+}
+
+// Column stands level's rank in for it
+func (dialect) Column(name string) (expr string, ok bool) {
+
+	switch name {
+	case "level":
+		expr, ok = "logs."+rankColumn, true
+	case parcours.PatternField:
+		expr, ok = "logs.pattern", true
+	}
+	return
+}
+
+func (dialect) Extract(path string) (expr string, args []any) {
+
+	expr = extract("?")
+	args = []any{path, path, path}
+	return
+}
+
+// Arg binds a value comparable with field: a rank for levels, formatted
+// time for timestamps, and text for everything but ids and patterns.
+func (dialect) Arg(name string, val any) (arg string, bound any, err error) {
+
+	arg = "?"
+	switch name {
+	case "id", parcours.PatternField:
+		bound = val
+	case "level":
+		bound = int(val.(parcours.Level))
+	case "timestamp":
+		bound = nullTime(val.(time.Time))
+	default:
+		if lvl, ok := val.(parcours.Level); ok {
+			val = lvl.String()
+		}
+		bound = fmt.Sprint(val)
+	}
+	return
+}
+
+func (dialect) Contains(expr string) string {
+	return fmt.Sprintf("instr(CAST(%s AS TEXT), ?) > 0", expr)
+}
+
+func (dialect) Match(expr string) string {
+	return fmt.Sprintf("CAST(%s AS TEXT) REGEXP ?", expr)
+}
+
+// extract a value from raw as text, as json_extract_string would,
+// strings unquoted and others as json, the path expression appearing thrice
+func extract(path string) string {
+	return fmt.Sprintf("(CASE json_type(raw, %[1]s) WHEN 'text' THEN raw ->> %[1]s WHEN 'null' THEN NULL ELSE raw -> %[1]s END)", path)
+}
+
+func quoteString(val string) string {
+	return "'" + strings.ReplaceAll(val, "'", "''") + "'"
+}
+
+// patterns caches compiled regexps for the regexp function backing REGEXP
+var patterns sync.Map
+
+func init() {
+
+	msqlite.MustRegisterDeterministicScalarFunction("regexp", 2, func(ctx *msqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+
+		pattern, ok := args[0].(string)
+		if !ok {
+			return nil, errors.Errorf("regexp expects a string pattern, got %T", args[0])
+		}
+		if args[1] == nil {
+			return nil, nil
+		}
+
+		re, ok := patterns.Load(pattern)
+		if !ok {
+			compiled, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to compile filter pattern")
+			}
+			re, _ = patterns.LoadOrStore(pattern, compiled)
+		}
+
+		return re.(*regexp.Regexp).MatchString(fmt.Sprint(args[1])), nil
+	})
+}
@@ -0,0 +1,20 @@
+package sqlite
+
+import (
+	"context"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// Frequencies of a field's values among lines matching filter, most
+// frequent first
+func (sq *Sqlite) Frequencies(ctx context.Context, filter parcours.Filter, field string, limit int) (freqs []parcours.Frequency, total int, err error) {
+
+	sq.mu.Lock()
+	fields := sq.fields()
+	sq.mu.Unlock()
+
+	freqs, total, err = sqlview.New(dialect{}, fields, false).Frequencies(ctx, sq.db, filter, field, limit)
+	return
+}
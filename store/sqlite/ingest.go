@@ -0,0 +1,142 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+)
+
+const (
+	// rankColumn orders levels by severity, generated from level
+	rankColumn = "level_rank"
+	// timeLayout is fixed width so that timestamps order as text
+	timeLayout = "2006-01-02 15:04:05.000000000-07:00"
+)
+
+// insert records with the patterns mined from their messages, publishing
+// them to any tail
+func (sq *Sqlite) insert(ctx context.Context, path string, recs []parcours.Record) (err error) {
+
+	if len(recs) == 0 {
+		return
+	}
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	tx, err := sq.db.BeginTx(ctx, nil)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to begin insert")
+		return
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO logs (id, timestamp, level, message, raw, pattern) VALUES (?, ?, NULLIF(?, ''), ?, ?, ?)")
+	if err != nil {
+		err = errors.Wrapf(err, "failed to prepare insert")
+		return
+	}
+	defer stmt.Close()
+
+	for i, rec := range recs {
+		var raw string
+		raw, err = encodeRaw(rec.Data)
+		if err != nil {
+			return
+		}
+
+		pattern := sq.miner.Add(rec.Message)
+		_, err = stmt.ExecContext(ctx, sq.lastID+int64(i)+1, nullTime(rec.Timestamp), rec.Level, rec.Message, raw, pattern)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to insert record")
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		err = errors.Wrapf(err, "failed to commit insert")
+		return
+	}
+
+	prevID := sq.lastID
+	sq.lastID += int64(len(recs))
+	sq.origins.Add(path, prevID+1, len(recs))
+
+	if !sq.tail.Active() {
+		return
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM logs WHERE id > ? ORDER BY id", selectList(sq.fields()))
+	lines, err := queryLines(ctx, sq.db, query, prevID)
+	if err != nil {
+		return
+	}
+	sq.tail.Publish(lines)
+	return
+}
+
+func createTables(db *sql.DB) (err error) {
+
+	// Level rank is generated so that levels sort and compare by severity
+	ranks := make([]string, len(parcours.Levels))
+	for i, lvl := range parcours.Levels {
+		ranks[i] = fmt.Sprintf("WHEN '%s' THEN %d", lvl, parcours.NormalizeLevel(lvl))
+	}
+
+	// Core fields as columns, others extracted from raw JSON, or promoted later
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS logs (
+			id INTEGER PRIMARY KEY,
+			timestamp TIMESTAMP,
+			level TEXT,
+			message TEXT,
+			raw TEXT,
+			pattern INTEGER,
+			%s INTEGER GENERATED ALWAYS AS (CASE level %s END) VIRTUAL
+		)`, rankColumn, strings.Join(ranks, " ")),
+		"CREATE INDEX IF NOT EXISTS idx_timestamp ON logs(timestamp)",
+	}
+
+	for _, stmt := range stmts {
+		_, err = db.Exec(stmt)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to create table")
+			return
+		}
+	}
+	return
+}
+
+func encodeRaw(data map[string]any) (raw string, err error) {
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	err = encoder.Encode(data)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to encode raw JSON")
+		return
+	}
+
+	raw = string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return
+}
+
+// nullTime formats a timestamp as text in UTC, nil when zero
+func nullTime(ts time.Time) any {
+
+	if ts.IsZero() {
+		return nil
+	}
+	return ts.UTC().Format(timeLayout)
+}
@@ -0,0 +1,24 @@
+package sqlite
+
+import (
+	"context"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// Patterns of lines in view, most frequent first
+func (sq *Sqlite) Patterns(ctx context.Context) (patterns []parcours.Pattern, err error) {
+
+	sq.mu.Lock()
+	err = sq.refreshSurround(ctx)
+	fields := sq.fields()
+	filter, surround := sq.filter, sq.surround.Active()
+	sq.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	patterns, err = sqlview.New(dialect{}, fields, surround).Patterns(ctx, sq.db, filter, sq.miner)
+	return
+}
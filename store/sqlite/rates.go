@@ -0,0 +1,116 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// micros renders a timestamp column, text in timeLayout, as microseconds
+// since the epoch
+const micros = "(CAST(strftime('%%s', substr(%[1]s, 1, 19)) AS INTEGER) * 1000000 + CAST(substr(%[1]s, 21, 6) AS INTEGER))"
+
+// Rates of lines in view by field's value, in buckets of about a buckets'th
+// of the time they span
+func (sq *Sqlite) Rates(ctx context.Context, field string, buckets int) (rates []parcours.Rate, width time.Duration, err error) {
+
+	sq.mu.Lock()
+	err = sq.refreshSurround(ctx)
+	fields := sq.fields()
+	filter, surround := sq.filter, sq.surround.Active()
+	sq.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	vw := sqlview.New(dialect{}, fields, surround)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+
+	var first, last sql.NullString
+	query := fmt.Sprintf("SELECT MIN(logs.timestamp), MAX(logs.timestamp) FROM logs WHERE %s", cond)
+	err = sq.db.QueryRowContext(ctx, query, vw.Args...).Scan(&first, &last)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query time span")
+		return
+	}
+	if !first.Valid {
+		return
+	}
+	span, err := parseSpan(first.String, last.String)
+	if err != nil {
+		return
+	}
+	width = parcours.BucketWidth(span, buckets)
+
+	// buckets by microseconds since the epoch, rounding down before it too,
+	// the first line in each by time
+	vw = sqlview.New(dialect{}, fields, surround)
+	value := vw.Value(field)
+	cond, err = vw.Lines(filter)
+	if err != nil {
+		return
+	}
+	query = fmt.Sprintf(`
+		SELECT bucket, value, COUNT(*) AS count, MIN(CASE WHEN pos = 1 THEN id END) AS first FROM (
+			SELECT bucket, value, id, row_number() OVER (PARTITION BY bucket, value ORDER BY timestamp, id) AS pos FROM (
+				SELECT (us - ((us %% %[1]d) + %[1]d) %% %[1]d) / %[1]d AS bucket, value, id, timestamp FROM (
+					SELECT %[2]s AS us, COALESCE(CAST(%[3]s AS TEXT), '') AS value, logs.id AS id, logs.timestamp AS timestamp
+					FROM logs WHERE %[4]s AND logs.timestamp IS NOT NULL
+				)
+			)
+		) GROUP BY bucket, value ORDER BY bucket, value`,
+		width.Microseconds(), fmt.Sprintf(micros, "logs.timestamp"), value, cond)
+
+	rows, err := sq.db.QueryContext(ctx, query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to count lines over time")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucket, first int64
+		var rate parcours.Rate
+		err = rows.Scan(&bucket, &rate.Value, &rate.Count, &first)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan rate")
+			return
+		}
+		rate.Start = time.UnixMicro(bucket * width.Microseconds()).UTC()
+		rate.First = fmt.Sprint(first)
+		rates = append(rates, rate)
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating rates")
+	return
+}
+
+// unexported
+
+// parseSpan between timestamps as stored
+func parseSpan(first, last string) (span time.Duration, err error) {
+
+	from, err := time.Parse(timeLayout, first)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse first timestamp")
+		return
+	}
+	to, err := time.Parse(timeLayout, last)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse last timestamp")
+		return
+	}
+
+	span = to.Sub(from)
+	return
+}
@@ -0,0 +1,436 @@
+// Package sqlite is a Store on SQLite, pure Go by way of modernc.org/sqlite,
+// keeping raw records as JSON alongside the core columns so that a session
+// saved to a file can be picked over with ordinary sqlite tools.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+type Sqlite struct {
+	db       *sql.DB
+	logger   parcours.Logger
+	decoder  *parcours.Decoder
+	filter   parcours.Filter
+	sorts    []parcours.Sort
+	tail     parcours.Broadcast
+	progress parcours.Tracker
+	mu       sync.Mutex
+	promoted []string
+	lastID   int64
+	// lines around matches, taken up to surrounded
+	surround   parcours.Surround
+	surrounded int64
+	miner      *parcours.Miner
+	origins    parcours.Origins
+}
+
+// New creates a Sqlite store in the database file at path, or in memory
+// when path is empty, decoding ndjson when dec is nil.
+// An existing database is picked up where it left off.
+func New(path string, dec *parcours.Decoder, lgr parcours.Logger) (sq *Sqlite, err error) {
+
+	if dec == nil {
+		dec, err = parcours.NewDecoder(nil, nil)
+		if err != nil {
+			return
+		}
+	}
+
+	if path == "" {
+		path = ":memory:"
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to open sqlite")
+		return
+	}
+	// a single connection, as each would have its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	err = createTables(db)
+	if err != nil {
+		db.Close()
+		return
+	}
+
+	sq = &Sqlite{
+		db:      db,
+		logger:  lgr,
+		decoder: dec,
+		miner:   parcours.NewMiner(),
+	}
+
+	err = sq.resume()
+	if err != nil {
+		db.Close()
+	}
+	return
+}
+
+func (sq *Sqlite) Close() {
+	sq.db.Close()
+}
+
+// Load a file
+func (sq *Sqlite) Load(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	src, err := parcours.OpenSource(path, sq.decoder)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	defer sq.progress.Finish()
+
+	sq.progress.Start("loading", src.Size(), 0)
+	src.Track(&sq.progress)
+
+	err = src.Load(ctx, opts, true, sq.insert)
+	return
+}
+
+// Follow a file, loading what's there and then polling for more
+func (sq *Sqlite) Follow(ctx context.Context, path string, opts parcours.LoadOptions) (err error) {
+
+	src, err := parcours.OpenSource(path, sq.decoder)
+	if err != nil {
+		return
+	}
+
+	defer sq.progress.Finish()
+
+	sq.progress.Start("loading", src.Size(), 0)
+	src.Track(&sq.progress)
+
+	err = src.Load(ctx, opts, false, sq.insert)
+	src.Track(nil)
+	if err != nil {
+		src.Close()
+		return
+	}
+
+	go func() {
+		defer src.Close()
+		src.Follow(ctx, sq.logger, opts, sq.insert)
+	}()
+	return
+}
+
+// Promote a field to a generated column, indexed
+func (sq *Sqlite) Promote(ctx context.Context, field string) (err error) {
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	if slices.Contains(sq.promoted, field) || isColumn(field) {
+		return
+	}
+
+	stmts := []string{
+		fmt.Sprintf("ALTER TABLE logs ADD COLUMN %s TEXT GENERATED ALWAYS AS (%s) VIRTUAL",
+			sqlview.QuoteIdent(field), extract(quoteString(sqlview.JsonPath(field)))),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON logs(%s)",
+			sqlview.QuoteIdent("idx_"+field), sqlview.QuoteIdent(field)),
+	}
+	for _, stmt := range stmts {
+		_, err = sq.db.ExecContext(ctx, stmt)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to promote field %s", field)
+			return
+		}
+	}
+
+	sq.promoted = append(sq.promoted, field)
+	return
+}
+
+// SetView Filter and Sort(s)
+func (sq *Sqlite) SetView(ctx context.Context, filter parcours.Filter, sorts []parcours.Sort) (err error) {
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	sq.filter = filter
+	sq.sorts = sorts
+	if !sq.surround.Active() {
+		return
+	}
+	err = sq.surroundLines(ctx)
+	return
+}
+
+// GetView fields and count
+func (sq *Sqlite) GetView(ctx context.Context) (fields []parcours.Field, count int, err error) {
+
+	sq.mu.Lock()
+	err = sq.refreshSurround(ctx)
+	fields = sq.fields()
+	filter, surround := sq.filter, sq.surround.Active()
+	sq.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	vw := sqlview.New(dialect{}, fields, surround)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+	if surround {
+		fields = parcours.SurroundFields(fields)
+	}
+
+	err = sq.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM logs WHERE "+cond, vw.Args...).Scan(&count)
+	err = errors.Wrapf(err, "failed to count logs")
+	return
+}
+
+// GetPage of log lines
+func (sq *Sqlite) GetPage(ctx context.Context, offset, size int) (lines []parcours.Line, err error) {
+
+	sq.mu.Lock()
+	fields := sq.fields()
+	filter, sorts, surround := sq.filter, sq.viewSorts(), sq.surround.Active()
+	sq.mu.Unlock()
+
+	vw := sqlview.New(dialect{}, fields, surround)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+	order := vw.OrderBy(sorts, false)
+
+	query := fmt.Sprintf("SELECT %s FROM logs WHERE %s %s LIMIT %d OFFSET %d",
+		vw.Selection(selectList(fields)), cond, order, size, offset)
+
+	lines, err = queryLines(ctx, sq.db, query, vw.Args...)
+	matched(lines, len(fields))
+	return
+}
+
+// GetPageAt returns lines after or before cursor, keyed on the sort fields
+// and id rather than offset
+func (sq *Sqlite) GetPageAt(ctx context.Context, cursor parcours.Cursor, size int) (lines []parcours.Line, err error) {
+
+	sq.mu.Lock()
+	fields := sq.fields()
+	filter, sorts, surround := sq.filter, sq.viewSorts(), sq.surround.Active()
+	sq.mu.Unlock()
+
+	vw := sqlview.New(dialect{}, fields, surround)
+	query, err := vw.Seek(selectList(fields), filter, sorts, cursor, size)
+	if err != nil {
+		return
+	}
+
+	lines, err = queryLines(ctx, sq.db, query, vw.Args...)
+	matched(lines, len(fields))
+	if cursor.Before {
+		slices.Reverse(lines)
+	}
+	return
+}
+
+// Locate the offset in the view of the line with id, or of its nearest
+// neighbour when it's filtered out
+func (sq *Sqlite) Locate(ctx context.Context, id string) (offset int, err error) {
+
+	sq.mu.Lock()
+	fields := sq.fields()
+	filter, sorts, surround := sq.filter, sq.viewSorts(), sq.surround.Active()
+	sq.mu.Unlock()
+
+	vw := sqlview.New(dialect{}, fields, surround)
+	query, err := vw.Locate(filter, sorts, id)
+	if err != nil {
+		return
+	}
+
+	var count int
+	err = sq.db.QueryRowContext(ctx, query, vw.Args...).Scan(&offset, &count)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to locate line %s", id)
+		return
+	}
+
+	offset = max(min(offset, count-1), 0)
+	return
+}
+
+// Source of the line with id, known for lines loaded since the database
+// was opened
+func (sq *Sqlite) Source(ctx context.Context, id string) (path string, err error) {
+	return sq.origins.Path(id)
+}
+
+// GetJson returns raw json for a line
+func (sq *Sqlite) GetJson(ctx context.Context, id string) (data map[string]any, err error) {
+
+	var raw string
+	err = sq.db.QueryRowContext(ctx, "SELECT raw FROM logs WHERE id = ?", id).Scan(&raw)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query raw JSON")
+		return
+	}
+
+	data, err = parcours.UnmarshalRaw([]byte(raw))
+	return
+}
+
+// Progress of a load
+func (sq *Sqlite) Progress() parcours.Progress {
+	return sq.progress.Progress()
+}
+
+// Tail streams log lines as they are inserted, until ctx is done
+func (sq *Sqlite) Tail(ctx context.Context) (lines <-chan parcours.Line, err error) {
+
+	lines = sq.tail.Subscribe(ctx)
+	return
+}
+
+// unexported
+
+var columns = []parcours.Field{
+	{Name: "id", Type: "BIGINT"},
+	{Name: "timestamp", Type: "TIMESTAMP"},
+	{Name: "level", Type: "VARCHAR"},
+	{Name: "message", Type: "VARCHAR"},
+}
+
+func isColumn(field string) bool {
+	return slices.ContainsFunc(columns, func(col parcours.Field) bool { return col.Name == field })
+}
+
+// fields of lines, core columns and then promoted
+// mu is expected to be held
+func (sq *Sqlite) fields() (fields []parcours.Field) {
+
+	fields = slices.Clone(columns)
+	for _, name := range sq.promoted {
+		fields = append(fields, parcours.Field{Name: name, Type: "VARCHAR"})
+	}
+	return
+}
+
+// resume from an existing database, picking up the last id and promoted columns
+func (sq *Sqlite) resume() (err error) {
+
+	err = sq.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM logs").Scan(&sq.lastID)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query last id")
+		return
+	}
+
+	// hidden is 2 for virtual generated columns
+	rows, err := sq.db.Query("SELECT name FROM pragma_table_xinfo('logs') WHERE hidden = 2 AND name != ? ORDER BY cid", rankColumn)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query promoted columns")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan promoted column")
+			return
+		}
+		sq.promoted = append(sq.promoted, name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		err = errors.Wrapf(err, "error iterating promoted columns")
+		return
+	}
+
+	err = sq.remine()
+	return
+}
+
+// remine messages in order, so that the miner gives the ids it gave before
+// for patterns to come
+func (sq *Sqlite) remine() (err error) {
+
+	rows, err := sq.db.Query("SELECT message FROM logs ORDER BY id")
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query messages")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message string
+		err = rows.Scan(&message)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan message")
+			return
+		}
+		sq.miner.Add(message)
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating messages")
+	return
+}
+
+func selectList(fields []parcours.Field) string {
+
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = "logs." + sqlview.QuoteIdent(field.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func queryLines(ctx context.Context, db *sql.DB, query string, args ...any) (lines []parcours.Line, err error) {
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to query logs")
+		return
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		err = errors.Wrapf(err, "failed to get cols from query rows")
+		return
+	}
+
+	for rows.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		err = rows.Scan(ptrs...)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan row")
+			return
+		}
+
+		line := make(parcours.Line, len(cols))
+		for i, val := range vals {
+			line[i] = parcours.Value{Raw: val}
+		}
+		lines = append(lines, line)
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating rows")
+	return
+}
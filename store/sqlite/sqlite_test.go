@@ -0,0 +1,22 @@
+package sqlite
+
+import (
+	"testing"
+
+	"parcours"
+	"parcours/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+
+	storetest.Run(t, func(t *testing.T, dec *parcours.Decoder) parcours.Store {
+
+		sq, err := New("", dec, storetest.Logger{T: t})
+		if err != nil {
+			t.Fatalf("failed to create sqlite store: %+v", err)
+		}
+		t.Cleanup(sq.Close)
+
+		return sq
+	})
+}
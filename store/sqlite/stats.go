@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// Summarize field over lines in view with a numeric value for it, grouped
+// by the value of by unless empty, largest groups first.
+// SQLite has no quantiles, so values are summarized as they're read.
+func (sq *Sqlite) Summarize(ctx context.Context, field, by string, limit int) (stats []parcours.Stats, err error) {
+
+	sq.mu.Lock()
+	err = sq.refreshSurround(ctx)
+	fields := sq.fields()
+	filter, surround := sq.filter, sq.surround.Active()
+	sq.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	// rendered in the order they appear, as they may take args
+	vw := sqlview.New(dialect{}, fields, surround)
+	group := "''"
+	if by != "" {
+		group = fmt.Sprintf("COALESCE(CAST(%s AS TEXT), '')", vw.Value(by))
+	}
+	value := vw.Value(field)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+
+	query := fmt.Sprintf(`
+		SELECT grp, x FROM (
+			SELECT %s AS grp, CAST(%s AS TEXT) AS x FROM logs WHERE %s
+		) WHERE x IS NOT NULL`, group, value, cond)
+
+	rows, err := sq.db.QueryContext(ctx, query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to summarize %s", field)
+		return
+	}
+	defer rows.Close()
+
+	groups := map[string][]float64{}
+	for rows.Next() {
+		var grp, text string
+		err = rows.Scan(&grp, &text)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan value")
+			return
+		}
+		// as DuckDB casts text to a double
+		num, parseErr := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if parseErr != nil {
+			continue
+		}
+		groups[grp] = append(groups[grp], num)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		err = errors.Wrapf(err, "error iterating values")
+		return
+	}
+
+	stats = parcours.SummarizeGroups(groups, limit)
+	return
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+	"parcours/store/sqlview"
+)
+
+// SetSurround for the view
+func (sq *Sqlite) SetSurround(ctx context.Context, surround parcours.Surround) (err error) {
+
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	sq.surround = surround
+	if !surround.Active() {
+		return
+	}
+	err = sq.surroundLines(ctx)
+	return
+}
+
+// unexported
+
+// viewSorts order the view, the stream's while surrounding
+// mu is expected to be held
+func (sq *Sqlite) viewSorts() []parcours.Sort {
+
+	if sq.surround.Active() {
+		return sq.surround.Sorts()
+	}
+	return sq.sorts
+}
+
+// refreshSurround takes lines ingested since into the surround table
+// mu is expected to be held
+func (sq *Sqlite) refreshSurround(ctx context.Context) (err error) {
+
+	if !sq.surround.Active() || sq.surrounded == sq.lastID {
+		return
+	}
+	err = sq.surroundLines(ctx)
+	return
+}
+
+// surroundLines takes matches of the filter and lines within reach of them
+// in the stream into the surround table, runs of adjacent lines sharing a
+// group.
+// mu is expected to be held
+func (sq *Sqlite) surroundLines(ctx context.Context) (err error) {
+
+	vw := sqlview.New(dialect{}, sq.fields(), false)
+	query, err := vw.Surround(sq.filter, sq.surround)
+	if err != nil {
+		return
+	}
+
+	_, err = sq.db.ExecContext(ctx, "DROP TABLE IF EXISTS temp.surround")
+	if err != nil {
+		err = errors.Wrapf(err, "failed to drop surround table")
+		return
+	}
+
+	_, err = sq.db.ExecContext(ctx, "CREATE TEMP TABLE surround AS "+query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to surround matches")
+		return
+	}
+
+	sq.surrounded = sq.lastID
+	return
+}
+
+// matched converts the match column at idx, integer in sqlite, to bool
+func matched(lines []parcours.Line, idx int) {
+
+	for _, line := range lines {
+		if idx < len(line) {
+			num, ok := line[idx].Raw.(int64)
+			if ok {
+				line[idx].Raw = num != 0
+			}
+		}
+	}
+}
@@ -0,0 +1,482 @@
+// Package sqlview renders views of logs, their filter and sorts, as SQL for
+// stores on SQL databases, leaving what differs between them to a Dialect.
+//
+// Lines are expected in a logs table with an id column, a column per core
+// and promoted field, and raw JSON for the rest.
+package sqlview
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"parcours"
+)
+
+// Dialect renders what differs between SQL databases.
+type Dialect interface {
+	// Same is the operator comparing values as equal, nulls included
+	Same() string
+	// Column renders a field kept other than as a plain column of logs, such
+	// as level or pattern, ok when it is
+	Column(name string) (expr string, ok bool)
+	// Extract renders a field from raw JSON as text, with the args it takes
+	Extract(path string) (expr string, args []any)
+	// Arg renders a placeholder for val compared with the named field, with
+	// the arg it takes. Levels are given as parcours.Level and timestamps as
+	// time.Time.
+	Arg(name string, val any) (arg string, bound any, err error)
+	// Contains renders a condition for expr as text containing ?
+	Contains(expr string) string
+	// Match renders a condition for expr as text matching the regexp ?
+	Match(expr string) string
+}
+
+// View renders filter and sorts as where and order by clauses for logs,
+// taking fields that aren't columns from raw JSON, or while surrounding,
+// lines from the surround table.
+// Args are those the rendered SQL takes, in order.
+type View struct {
+	Args     []any
+	dialect  Dialect
+	columns  map[string]bool
+	surround bool
+}
+
+// New view of lines with fields as columns.
+func New(dialect Dialect, fields []parcours.Field, surround bool) *View {
+
+	vw := &View{dialect: dialect, columns: map[string]bool{}, surround: surround}
+	for _, field := range fields {
+		vw.columns[field.Name] = true
+	}
+	return vw
+}
+
+// Lines renders the condition for lines in view: matches of filter, or
+// while surrounding, those taken around them.
+func (vw *View) Lines(filter parcours.Filter) (cond string, err error) {
+
+	if vw.surround {
+		cond = "logs.id IN (SELECT id FROM surround)"
+		return
+	}
+	return vw.Where(filter)
+}
+
+// Selection of cols, with whether lines matched and their group while
+// surrounding.
+func (vw *View) Selection(cols string) string {
+
+	if !vw.surround {
+		return cols
+	}
+	for _, name := range []string{parcours.MatchField, parcours.GroupField} {
+		cols += fmt.Sprintf(", (SELECT %[1]s FROM surround WHERE surround.id = logs.id) AS %[1]s", QuoteIdent(name))
+	}
+	return cols
+}
+
+// Where renders a filter as a condition, true for the zero filter.
+func (vw *View) Where(filter parcours.Filter) (cond string, err error) {
+
+	switch filter.Op {
+	case parcours.And, parcours.Or:
+		if len(filter.Children) == 0 {
+			cond = "TRUE"
+			if filter.Op == parcours.Or {
+				cond = "FALSE"
+			}
+			return
+		}
+
+		join := " AND "
+		if filter.Op == parcours.Or {
+			join = " OR "
+		}
+
+		conds := make([]string, len(filter.Children))
+		for i, child := range filter.Children {
+			conds[i], err = vw.Where(*child)
+			if err != nil {
+				return
+			}
+		}
+		cond = "(" + strings.Join(conds, join) + ")"
+
+	case parcours.Not:
+		if len(filter.Children) != 1 {
+			err = errors.Errorf("not filter expects one child, got %d", len(filter.Children))
+			return
+		}
+		cond, err = vw.Where(*filter.Children[0])
+		cond = "(NOT " + cond + ")"
+
+	default:
+		cond, err = vw.compare(filter)
+	}
+	return
+}
+
+// OrderBy renders sorts as an order by clause, with id breaking ties,
+// reversed to page back from the end.
+func (vw *View) OrderBy(sorts []parcours.Sort, reverse bool) (order string) {
+
+	var terms []string
+	for _, sort := range sorts {
+		term := vw.Field(sort.Field)
+		if sort.Desc != reverse {
+			term += " DESC"
+		}
+		if reverse {
+			term += " NULLS FIRST"
+		} else {
+			term += " NULLS LAST"
+		}
+		terms = append(terms, term)
+	}
+	if reverse {
+		terms = append(terms, "logs.id DESC")
+	} else {
+		terms = append(terms, "logs.id")
+	}
+
+	order = "ORDER BY " + strings.Join(terms, ", ")
+	return
+}
+
+// Seek renders a query for a page of lines after cursor, or before it in
+// reverse for the caller to flip, keyed on the cursor row's sort values and id.
+func (vw *View) Seek(cols string, filter parcours.Filter, sorts []parcours.Sort, cursor parcours.Cursor, size int) (query string, err error) {
+
+	from := "logs"
+	if cursor.ID != "" {
+		from = vw.cursor(sorts, cursor.ID)
+	}
+
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+	if cursor.ID != "" {
+		cond += " AND " + vw.keyset(sorts, cursor)
+	}
+	order := vw.OrderBy(sorts, cursor.Before)
+
+	query = fmt.Sprintf("SELECT %s FROM %s WHERE %s %s LIMIT %d", vw.Selection(cols), from, cond, order, size)
+	return
+}
+
+// Locate renders a query counting lines in view before the position of
+// the line with id, and in view altogether, counting none when it's gone.
+func (vw *View) Locate(filter parcours.Filter, sorts []parcours.Sort, id string) (query string, err error) {
+
+	// rendered apart, as the args of each come in the order they appear
+	before, keys, conds := vw.sibling(), vw.sibling(), vw.sibling()
+	keyset := before.keyset(sorts, parcours.Cursor{ID: id, Before: true})
+	from := keys.cursor(sorts, id)
+	cond, err := conds.Lines(filter)
+	if err != nil {
+		return
+	}
+
+	query = fmt.Sprintf("SELECT COUNT(*) FILTER (WHERE %s), COUNT(*) FROM %s WHERE %s", keyset, from, cond)
+	vw.Args = slices.Concat(vw.Args, before.Args, keys.Args, conds.Args)
+	return
+}
+
+// Surround renders a query for matches of filter and lines within reach of
+// them in the stream, runs of adjacent lines sharing a group, to be taken
+// into the surround table.
+func (vw *View) Surround(filter parcours.Filter, surround parcours.Surround) (query string, err error) {
+
+	cond, err := vw.Where(filter)
+	if err != nil {
+		return
+	}
+	order := vw.OrderBy(surround.Sorts(), false)
+
+	query = fmt.Sprintf(`
+		WITH stream AS (
+			SELECT logs.id, COALESCE(%[1]s, FALSE) AS hit, row_number() OVER (%[2]s) AS pos FROM logs
+		), near AS (
+			SELECT id, hit, pos,
+				max(hit) OVER (ORDER BY pos ROWS BETWEEN %[3]d PRECEDING AND %[3]d FOLLOWING) AS near
+			FROM stream
+		)
+		SELECT id, hit AS %[4]s, pos - row_number() OVER (ORDER BY pos) AS %[5]s FROM near WHERE near`,
+		cond, order, surround.Lines, QuoteIdent(parcours.MatchField), QuoteIdent(parcours.GroupField))
+	return
+}
+
+// Frequencies of a field's values among lines in db matching filter, most
+// frequent first.
+func (vw *View) Frequencies(ctx context.Context, db *sql.DB, filter parcours.Filter, field string, limit int) (freqs []parcours.Frequency, total int, err error) {
+
+	val := vw.Value(field)
+	cond, err := vw.Where(filter)
+	if err != nil {
+		return
+	}
+
+	// totalled over every value before the limit, nulls included though unlisted
+	query := fmt.Sprintf(`
+		SELECT CAST(%s AS VARCHAR) AS value, COUNT(*) AS count, CAST(SUM(COUNT(*)) OVER () AS BIGINT) AS total
+		FROM logs WHERE %s GROUP BY value ORDER BY value IS NULL, count DESC, value LIMIT %d`, val, cond, limit)
+
+	rows, err := db.QueryContext(ctx, query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to count values of %s", field)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value sql.NullString
+		var count int
+		err = rows.Scan(&value, &count, &total)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan value count")
+			return
+		}
+		if value.Valid {
+			freqs = append(freqs, parcours.Frequency{Value: value.String, Count: count})
+		}
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating value counts")
+	return
+}
+
+// Patterns of lines in db in view, most frequent first, with templates
+// from the miner that mined them.
+func (vw *View) Patterns(ctx context.Context, db *sql.DB, filter parcours.Filter, miner *parcours.Miner) (patterns []parcours.Pattern, err error) {
+
+	pattern := vw.Field(parcours.PatternField)
+	cond, err := vw.Lines(filter)
+	if err != nil {
+		return
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s AS pattern, COUNT(*) AS count FROM logs
+		WHERE %s GROUP BY pattern ORDER BY count DESC, pattern`, pattern, cond)
+
+	rows, err := db.QueryContext(ctx, query, vw.Args...)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to count patterns")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pattern parcours.Pattern
+		err = rows.Scan(&pattern.ID, &pattern.Count)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to scan pattern count")
+			return
+		}
+		pattern.Template = miner.Template(pattern.ID)
+		patterns = append(patterns, pattern)
+	}
+
+	err = rows.Err()
+	err = errors.Wrapf(err, "error iterating pattern counts")
+	return
+}
+
+// Field renders a column, one the dialect keeps its own way, or an extract
+// from raw for fields not promoted.
+func (vw *View) Field(name string) string {
+
+	if expr, ok := vw.dialect.Column(name); ok {
+		return expr
+	}
+	if vw.columns[name] {
+		return "logs." + QuoteIdent(name)
+	}
+
+	expr, args := vw.dialect.Extract(JsonPath(name))
+	vw.Args = append(vw.Args, args...)
+	return expr
+}
+
+// Value renders a field as shown, levels by name rather than as compared.
+func (vw *View) Value(name string) string {
+
+	if name == "level" {
+		return "logs.level"
+	}
+	return vw.Field(name)
+}
+
+// QuoteIdent quotes an identifier, such as a field's column.
+func QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// JsonPath to a top-level field of raw, quoted so that any key is taken whole.
+func JsonPath(name string) string {
+	return "$." + QuoteIdent(name)
+}
+
+// unexported
+
+// sibling view, alike but without args
+func (vw *View) sibling() *View {
+	return &View{dialect: vw.dialect, columns: vw.columns, surround: vw.surround}
+}
+
+// cursor renders logs joined with the sort values and id of the line with
+// id, as cursor.key0 ... cursor.key_id
+func (vw *View) cursor(sorts []parcours.Sort, id string) string {
+
+	keys := make([]string, len(sorts))
+	for i, sort := range sorts {
+		keys[i] = fmt.Sprintf("%s AS key%d", vw.Field(sort.Field), i)
+	}
+	keys = append(keys, "logs.id AS key_id")
+	vw.Args = append(vw.Args, id)
+	return fmt.Sprintf("logs, (SELECT %s FROM logs WHERE logs.id = ?) AS cursor", strings.Join(keys, ", "))
+}
+
+// keyset renders a condition for rows beyond the cursor's, as
+// (s0 beyond) OR (s0 same AND s1 beyond) ... OR (all same AND id beyond)
+func (vw *View) keyset(sorts []parcours.Sort, cursor parcours.Cursor) string {
+
+	var terms []string
+	for i := range len(sorts) + 1 {
+		var parts []string
+		for j := range i {
+			parts = append(parts, fmt.Sprintf("%s %s cursor.key%d", vw.Field(sorts[j].Field), vw.dialect.Same(), j))
+		}
+
+		if i < len(sorts) {
+			parts = append(parts, vw.beyond(sorts[i], fmt.Sprintf("cursor.key%d", i), cursor.Before))
+		} else {
+			op := ">"
+			if cursor.Before {
+				op = "<"
+			}
+			if cursor.Inclusive {
+				op += "="
+			}
+			parts = append(parts, "logs.id "+op+" cursor.key_id")
+		}
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+// beyond renders a condition for a sort field past key in the direction
+// paged, nulls being last
+func (vw *View) beyond(sort parcours.Sort, key string, before bool) string {
+
+	op := "<"
+	if sort.Desc == before {
+		op = ">"
+	}
+
+	field := vw.Field(sort.Field)
+	if before {
+		return fmt.Sprintf("(%s %s %s OR (%s IS NULL AND %s IS NOT NULL))", field, op, key, key, vw.Field(sort.Field))
+	}
+	return fmt.Sprintf("(%s %s %s OR (%s IS NULL AND %s IS NOT NULL))", field, op, key, vw.Field(sort.Field), key)
+}
+
+func (vw *View) compare(filter parcours.Filter) (cond string, err error) {
+
+	if filter.Field == "" {
+		err = errors.Errorf("comparison filter expects a field")
+		return
+	}
+	field := vw.Field(filter.Field)
+
+	if filter.Value == nil {
+		switch filter.Op {
+		case parcours.Eq:
+			cond = field + " IS NULL"
+		case parcours.Ne:
+			cond = field + " IS NOT NULL"
+		default:
+			err = errors.Errorf("filter on %s compares with nil", filter.Field)
+		}
+		return
+	}
+
+	switch filter.Op {
+	case parcours.Contains:
+		vw.Args = append(vw.Args, fmt.Sprint(filter.Value))
+		cond = vw.dialect.Contains(field)
+		return
+	case parcours.Match:
+		vw.Args = append(vw.Args, fmt.Sprint(filter.Value))
+		cond = vw.dialect.Match(field)
+		return
+	}
+
+	val, err := operand(filter.Field, filter.Value)
+	if err != nil {
+		return
+	}
+	arg, bound, err := vw.dialect.Arg(filter.Field, val)
+	if err != nil {
+		return
+	}
+	vw.Args = append(vw.Args, bound)
+
+	switch filter.Op {
+	case parcours.Eq:
+		cond = field + " = " + arg
+	case parcours.Ne:
+		cond = field + " != " + arg
+	case parcours.Gt:
+		cond = field + " > " + arg
+	case parcours.Gte:
+		cond = field + " >= " + arg
+	case parcours.Lt:
+		cond = field + " < " + arg
+	case parcours.Lte:
+		cond = field + " <= " + arg
+	default:
+		err = errors.Errorf("unknown filter op: %d", filter.Op)
+	}
+	return
+}
+
+// operand for comparing with the named field: levels normalized so they
+// compare by severity, and timestamps given as text or epochs parsed, as
+// they're sent by remote clients and written in filter expressions
+func operand(name string, val any) (cmp any, err error) {
+
+	switch name {
+	case "level":
+		lvl := parcours.NormalizeLevel(val)
+		if lvl == parcours.LevelUnknown {
+			err = errors.Errorf("unknown level: %v", val)
+			return
+		}
+		cmp = lvl
+
+	case "timestamp":
+		ts, ok := val.(time.Time)
+		if !ok {
+			ts = parcours.ParseTime(val, "")
+		}
+		if ts.IsZero() {
+			err = errors.Errorf("cannot compare timestamp with %v", val)
+			return
+		}
+		cmp = ts
+
+	default:
+		cmp = val
+	}
+	return
+}
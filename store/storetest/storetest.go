@@ -0,0 +1,1304 @@
+// Package storetest provides a conformance suite for parcours.Store implementations.
+//
+// Stores are expected to present lines as id, timestamp, level and message,
+// followed by any promoted fields, ordered by id unless sorted otherwise.
+// Ids are assigned in load order, starting from 1.
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"parcours"
+)
+
+// NewStore creates an empty store for a test, decoding lines with dec.
+// Stores are expected to clean up after themselves with t.Cleanup.
+type NewStore func(t *testing.T, dec *parcours.Decoder) parcours.Store
+
+// Run runs the conformance suite against stores from newStore.
+func Run(t *testing.T, newStore NewStore) {
+
+	t.Run("load", func(t *testing.T) { testLoad(t, newStore) })
+	t.Run("window", func(t *testing.T) { testWindow(t, newStore) })
+	t.Run("page", func(t *testing.T) { testPage(t, newStore) })
+	t.Run("filter", func(t *testing.T) { testFilter(t, newStore) })
+	t.Run("expr", func(t *testing.T) { testExpr(t, newStore) })
+	t.Run("sort", func(t *testing.T) { testSort(t, newStore) })
+	t.Run("seek", func(t *testing.T) { testSeek(t, newStore) })
+	t.Run("locate", func(t *testing.T) { testLocate(t, newStore) })
+	t.Run("correlate", func(t *testing.T) { testCorrelate(t, newStore) })
+	t.Run("surround", func(t *testing.T) { testSurround(t, newStore) })
+	t.Run("compare", func(t *testing.T) { testCompare(t, newStore) })
+	t.Run("patterns", func(t *testing.T) { testPatterns(t, newStore) })
+	t.Run("rates", func(t *testing.T) { testRates(t, newStore) })
+	t.Run("stats", func(t *testing.T) { testStats(t, newStore) })
+	t.Run("promote", func(t *testing.T) { testPromote(t, newStore) })
+	t.Run("json", func(t *testing.T) { testJson(t, newStore) })
+	t.Run("source", func(t *testing.T) { testSource(t, newStore) })
+	t.Run("levels", func(t *testing.T) { testLevels(t, newStore) })
+	t.Run("multiline", func(t *testing.T) { testMultiline(t, newStore) })
+	t.Run("parser", func(t *testing.T) { testParser(t, newStore) })
+	t.Run("tail", func(t *testing.T) { testTail(t, newStore) })
+	t.Run("follow files", func(t *testing.T) { testFollowFiles(t, newStore) })
+	t.Run("follow rotated", func(t *testing.T) { testFollowRotated(t, newStore) })
+	t.Run("cancel", func(t *testing.T) { testCancel(t, newStore) })
+	t.Run("concurrent view", func(t *testing.T) { testConcurrentView(t, newStore) })
+}
+
+// Fixture returns the path of a log file from test/data.
+func Fixture(name string) string {
+
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "test", "data", name)
+}
+
+// Logger logs to the test.
+type Logger struct {
+	T *testing.T
+}
+
+func (lgr Logger) Info(ctx context.Context, msg string, kv ...any) {
+	lgr.T.Logf("info: %s %v", msg, kv)
+}
+
+func (lgr Logger) Error(ctx context.Context, msg string, err error, kv ...any) {
+	lgr.T.Logf("error: %s: %v %v", msg, err, kv)
+}
+
+// unexported
+
+func testLoad(t *testing.T, newStore NewStore) {
+
+	tests := []struct {
+		name  string
+		last  int
+		count int
+		first string
+	}{
+		{name: "all", last: 0, count: 19, first: "loaded"},
+		{name: "last five", last: 5, count: 5, first: "worker shutting down"},
+		{name: "last more than there are", last: 99, count: 19, first: "loaded"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			st := newStore(t, decoder(t, nil, nil))
+			mustDo(t, st.Load(t.Context(), Fixture("smar.log"), parcours.LoadOptions{Last: tc.last}))
+
+			fields, count := view(t, st)
+			if count != tc.count {
+				t.Errorf("expected count %d, got %d", tc.count, count)
+			}
+			expectFields(t, fields, "id", "timestamp", "level", "message")
+
+			lines := page(t, st, 0, 1)
+			if msg := lines[0][3].String(); msg != tc.first {
+				t.Errorf("expected first message %q, got %q", tc.first, msg)
+			}
+		})
+	}
+}
+
+func testWindow(t *testing.T, newStore NewStore) {
+
+	start := time.Date(2025, 11, 13, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		opts    parcours.LoadOptions
+		count   int
+		firstTs time.Time
+	}{
+		{name: "since", opts: parcours.LoadOptions{Since: start.Add(5 * time.Second)}, count: 3, firstTs: start.Add(5 * time.Second)},
+		{name: "until", opts: parcours.LoadOptions{Until: start.Add(2 * time.Second)}, count: 3, firstTs: start},
+		{
+			name:    "since and until",
+			opts:    parcours.LoadOptions{Since: start.Add(2 * time.Second), Until: start.Add(5 * time.Second)},
+			count:   4,
+			firstTs: start.Add(2 * time.Second),
+		},
+		{
+			name:    "last within",
+			opts:    parcours.LoadOptions{Last: 2, Since: start.Add(2 * time.Second), Until: start.Add(5 * time.Second)},
+			count:   2,
+			firstTs: start.Add(4 * time.Second),
+		},
+		{name: "none within", opts: parcours.LoadOptions{Since: start.Add(time.Hour)}, count: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			st := newStore(t, decoder(t, nil, nil))
+			mustDo(t, st.Load(t.Context(), Fixture("levels.log"), tc.opts))
+
+			_, count := view(t, st)
+			if count != tc.count {
+				t.Fatalf("expected count %d, got %d", tc.count, count)
+			}
+			if count == 0 {
+				return
+			}
+
+			lines := page(t, st, 0, 1)
+			ts, err := lines[0][1].Time()
+			mustDo(t, err)
+			if !ts.Equal(tc.firstTs) {
+				t.Errorf("expected first timestamp %s, got %s", tc.firstTs, ts)
+			}
+		})
+	}
+}
+
+func testPage(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+
+	tests := []struct {
+		name    string
+		offset  int
+		size    int
+		firstID string
+		count   int
+	}{
+		{name: "first", offset: 0, size: 5, firstID: "1", count: 5},
+		{name: "middle", offset: 7, size: 4, firstID: "8", count: 4},
+		{name: "short last", offset: 17, size: 5, firstID: "18", count: 2},
+		{name: "beyond", offset: 19, size: 5, count: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := page(t, st, tc.offset, tc.size)
+			if len(lines) != tc.count {
+				t.Fatalf("expected %d lines, got %d", tc.count, len(lines))
+			}
+			if tc.count > 0 && lines[0][0].String() != tc.firstID {
+				t.Errorf("expected first id %s, got %s", tc.firstID, lines[0][0].String())
+			}
+		})
+	}
+}
+
+func testFilter(t *testing.T, newStore NewStore) {
+
+	tests := []struct {
+		name   string
+		filter parcours.Filter
+		count  int
+	}{
+		{name: "zero", filter: parcours.Filter{}, count: 19},
+		{name: "eq level", filter: eq("level", "debug"), count: 4},
+		{name: "eq message", filter: eq("message", "worker starting"), count: 2},
+		{name: "ne level", filter: parcours.Filter{Op: parcours.Ne, Field: "level", Value: "debug"}, count: 15},
+		{name: "contains", filter: parcours.Filter{Op: parcours.Contains, Field: "message", Value: "worker"}, count: 5},
+		{name: "match", filter: parcours.Filter{Op: parcours.Match, Field: "message", Value: "^shutting"}, count: 2},
+		{name: "raw field", filter: eq("worker_id", "X43f8xw"), count: 3},
+		{name: "raw field null", filter: parcours.Filter{Op: parcours.Eq, Field: "worker_id"}, count: 13},
+		{name: "raw field not null", filter: parcours.Filter{Op: parcours.Ne, Field: "worker_id"}, count: 6},
+		{name: "gt timestamp", filter: parcours.Filter{
+			Op: parcours.Gt, Field: "timestamp", Value: time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC),
+		}, count: 7},
+		{name: "not", filter: parcours.Filter{Op: parcours.Not, Children: []*parcours.Filter{
+			ptr(eq("level", "debug")),
+		}}, count: 15},
+		{name: "or", filter: parcours.Filter{Op: parcours.Or, Children: []*parcours.Filter{
+			ptr(eq("worker_id", "JKIACR5")),
+			{Op: parcours.Contains, Field: "message", Value: "http"},
+		}}, count: 6},
+		{name: "and", filter: parcours.Filter{Op: parcours.And, Children: []*parcours.Filter{
+			ptr(eq("level", "info")),
+			{Op: parcours.Contains, Field: "message", Value: "stop"},
+		}}, count: 4},
+		{name: "empty or", filter: parcours.Filter{Op: parcours.Or}, count: 0},
+	}
+
+	for _, promote := range []bool{false, true} {
+		st := loaded(t, newStore, "smar.log")
+		if promote {
+			mustDo(t, st.Promote(t.Context(), "worker_id"))
+		}
+
+		for _, tc := range tests {
+			name := tc.name
+			if promote {
+				name += " promoted"
+			}
+			t.Run(name, func(t *testing.T) {
+				mustDo(t, st.SetView(t.Context(), tc.filter, nil))
+
+				_, count := view(t, st)
+				if count != tc.count {
+					t.Errorf("expected count %d, got %d", tc.count, count)
+				}
+
+				lines := page(t, st, 0, 99)
+				if len(lines) != tc.count {
+					t.Errorf("expected %d lines, got %d", tc.count, len(lines))
+				}
+			})
+		}
+	}
+}
+
+// testExpr filters by expressions, as parsed and as rendered back
+func testExpr(t *testing.T, newStore NewStore) {
+
+	tests := []struct {
+		expr  string
+		count int
+	}{
+		{expr: "", count: 19},
+		{expr: "level=debug", count: 4},
+		{expr: "level!=DEBUG", count: 15},
+		{expr: "message*=worker", count: 5},
+		{expr: `message~"^shutting"`, count: 2},
+		{expr: "worker_id=X43f8xw", count: 3},
+		{expr: "worker_id=null", count: 13},
+		{expr: "not worker_id=null", count: 6},
+		{expr: `timestamp>"2025-11-13T20:30:00Z"`, count: 7},
+		{expr: "timestamp>2025-11-13T20:30:00Z", count: 7},
+		{expr: "timestamp<=2025-11-13T20:30:00+00:00", count: 12},
+		{expr: `worker_id=JKIACR5 or message*="http"`, count: 6},
+		{expr: "level=info and (message*=stop or message*=stopped)", count: 4},
+	}
+
+	st := loaded(t, newStore, "smar.log")
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			filter, err := parcours.ParseFilter(tc.expr)
+			mustDo(t, err)
+			mustDo(t, st.SetView(t.Context(), filter, nil))
+			if _, count := view(t, st); count != tc.count {
+				t.Errorf("expected count %d, got %d", tc.count, count)
+			}
+		})
+	}
+}
+
+func testSort(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+
+	tests := []struct {
+		name    string
+		sorts   []parcours.Sort
+		filter  parcours.Filter
+		firstID string
+		lastID  string
+	}{
+		{name: "none", firstID: "1", lastID: "19"},
+		{name: "timestamp desc", sorts: []parcours.Sort{{Field: "timestamp", Desc: true}}, firstID: "19", lastID: "1"},
+		{name: "timestamp", sorts: []parcours.Sort{{Field: "timestamp"}}, firstID: "1", lastID: "19"},
+		{name: "message", sorts: []parcours.Sort{{Field: "message"}}, firstID: "18", lastID: "17"},
+		{name: "level by severity", sorts: []parcours.Sort{{Field: "level"}}, firstID: "8", lastID: "19"},
+		{name: "level desc ties by id", sorts: []parcours.Sort{{Field: "level", Desc: true}}, firstID: "1", lastID: "11"},
+		{name: "raw field, nulls last", sorts: []parcours.Sort{{Field: "worker_id"}}, firstID: "6", lastID: "19"},
+		{name: "filtered", sorts: []parcours.Sort{{Field: "timestamp", Desc: true}}, filter: eq("level", "debug"), firstID: "11", lastID: "8"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mustDo(t, st.SetView(t.Context(), tc.filter, tc.sorts))
+
+			lines := page(t, st, 0, 99)
+			if len(lines) == 0 {
+				t.Fatalf("expected lines")
+			}
+			if id := lines[0][0].String(); id != tc.firstID {
+				t.Errorf("expected first id %s, got %s", tc.firstID, id)
+			}
+			if id := lines[len(lines)-1][0].String(); id != tc.lastID {
+				t.Errorf("expected last id %s, got %s", tc.lastID, id)
+			}
+		})
+	}
+}
+
+// testSeek pages by cursor through views, expecting the order GetPage gives,
+// for stores that are a Seeker
+func testSeek(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+	seeker, ok := st.(parcours.Seeker)
+	if !ok {
+		t.Skip("store does not page by cursor")
+	}
+
+	tests := []struct {
+		name   string
+		sorts  []parcours.Sort
+		filter parcours.Filter
+	}{
+		{name: "none"},
+		{name: "timestamp desc", sorts: []parcours.Sort{{Field: "timestamp", Desc: true}}},
+		{name: "message", sorts: []parcours.Sort{{Field: "message"}}},
+		{name: "level ties by id", sorts: []parcours.Sort{{Field: "level", Desc: true}}},
+		{name: "raw field, nulls last", sorts: []parcours.Sort{{Field: "worker_id"}}},
+		{name: "raw field desc, then level", sorts: []parcours.Sort{{Field: "worker_id", Desc: true}, {Field: "level"}}},
+		{name: "filtered", sorts: []parcours.Sort{{Field: "timestamp", Desc: true}}, filter: eq("level", "debug")},
+	}
+
+	seek := func(t *testing.T, cursor parcours.Cursor, size int) (ids []string) {
+		t.Helper()
+		lines, err := seeker.GetPageAt(t.Context(), cursor, size)
+		mustDo(t, err)
+		for _, ln := range lines {
+			ids = append(ids, ln[0].String())
+		}
+		return
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mustDo(t, st.SetView(t.Context(), parcours.Filter{}, tc.sorts))
+			var all []string
+			for _, ln := range page(t, st, 0, 99) {
+				all = append(all, ln[0].String())
+			}
+
+			mustDo(t, st.SetView(t.Context(), tc.filter, tc.sorts))
+			var want []string
+			for _, ln := range page(t, st, 0, 99) {
+				want = append(want, ln[0].String())
+			}
+
+			var forward []string
+			for cursor := (parcours.Cursor{}); ; {
+				ids := seek(t, cursor, 4)
+				forward = append(forward, ids...)
+				if len(ids) < 4 {
+					break
+				}
+				cursor = parcours.Cursor{ID: ids[len(ids)-1]}
+			}
+			if !slices.Equal(forward, want) {
+				t.Errorf("expected forward pages %v, got %v", want, forward)
+			}
+
+			var backward []string
+			for cursor := (parcours.Cursor{Before: true}); ; {
+				ids := seek(t, cursor, 4)
+				backward = append(ids, backward...)
+				if len(ids) < 4 {
+					break
+				}
+				cursor = parcours.Cursor{ID: ids[0], Before: true}
+			}
+			if !slices.Equal(backward, want) {
+				t.Errorf("expected backward pages %v, got %v", want, backward)
+			}
+
+			mid := want[len(want)/2]
+			if ids := seek(t, parcours.Cursor{ID: mid, Inclusive: true}, 2); len(ids) == 0 || ids[0] != mid {
+				t.Errorf("expected inclusive page to start with %s, got %v", mid, ids)
+			}
+			if ids := seek(t, parcours.Cursor{ID: mid, Before: true, Inclusive: true}, 2); len(ids) == 0 || ids[len(ids)-1] != mid {
+				t.Errorf("expected inclusive page back to end with %s, got %v", mid, ids)
+			}
+
+			// from lines out of view, expect what's in view past their position
+			for pos, id := range all {
+				if slices.Contains(want, id) {
+					continue
+				}
+				var after []string
+				for _, other := range all[pos+1:] {
+					if slices.Contains(want, other) {
+						after = append(after, other)
+					}
+				}
+				if ids := seek(t, parcours.Cursor{ID: id}, 99); !slices.Equal(ids, after) {
+					t.Errorf("expected lines after %s out of view to be %v, got %v", id, after, ids)
+				}
+			}
+		})
+	}
+
+	if ids := seek(t, parcours.Cursor{ID: "999"}, 4); len(ids) != 0 {
+		t.Errorf("expected no lines from a missing cursor, got %v", ids)
+	}
+}
+
+// testLocate expects lines in view at their offset, and those out of it at
+// the offset of the next line in view, or else the last
+func testLocate(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+
+	tests := []struct {
+		name   string
+		sorts  []parcours.Sort
+		filter parcours.Filter
+	}{
+		{name: "none"},
+		{name: "message", sorts: []parcours.Sort{{Field: "message"}}},
+		{name: "raw field desc, then level", sorts: []parcours.Sort{{Field: "worker_id", Desc: true}, {Field: "level"}}},
+		{name: "filtered", sorts: []parcours.Sort{{Field: "timestamp", Desc: true}}, filter: eq("level", "debug")},
+		{name: "nothing in view", filter: eq("level", "fatal")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mustDo(t, st.SetView(t.Context(), parcours.Filter{}, tc.sorts))
+			var all []string
+			for _, ln := range page(t, st, 0, 99) {
+				all = append(all, ln[0].String())
+			}
+
+			mustDo(t, st.SetView(t.Context(), tc.filter, tc.sorts))
+			var want []string
+			for _, ln := range page(t, st, 0, 99) {
+				want = append(want, ln[0].String())
+			}
+
+			next := 0
+			for _, id := range all {
+				expected := max(min(next, len(want)-1), 0)
+				if next < len(want) && want[next] == id {
+					next++
+				}
+
+				offset, err := st.Locate(t.Context(), id)
+				mustDo(t, err)
+				if offset != expected {
+					t.Errorf("expected line %s at offset %d, got %d", id, expected, offset)
+				}
+			}
+		})
+	}
+}
+
+// testCorrelate views lines sharing ids with another, across two loads of
+// the same file
+func testCorrelate(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+	mustDo(t, st.Load(t.Context(), Fixture("smar.log"), parcours.LoadOptions{}))
+
+	tests := []struct {
+		name   string
+		id     string
+		fields []string
+		ids    []string
+	}{
+		{name: "worker", id: "3", fields: []string{"worker_id"}, ids: []string{"3", "22", "15", "34", "16", "35"}},
+		{name: "missing skipped", id: "12", fields: []string{"status", "worker_id"}, ids: []string{"6", "25", "12", "31", "17", "36"}},
+		{name: "numbers", id: "9", fields: []string{"status"}, ids: []string{"9", "28", "11", "30"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, _, err := parcours.Correlate(t.Context(), st, tc.id, tc.fields)
+			mustDo(t, err)
+			mustDo(t, st.SetView(t.Context(), filter, []parcours.Sort{{Field: "timestamp"}}))
+
+			var ids []string
+			for _, ln := range page(t, st, 0, 99) {
+				ids = append(ids, ln[0].String())
+			}
+			if !slices.Equal(ids, tc.ids) {
+				t.Errorf("expected %v, got %v", tc.ids, ids)
+			}
+		})
+	}
+
+	_, _, err := parcours.Correlate(t.Context(), st, "1", []string{"worker_id"})
+	if err == nil {
+		t.Errorf("expected error correlating a line without ids")
+	}
+
+	// ids past 2^53, alike as floats
+	path := filepath.Join(t.TempDir(), "ids.log")
+	mustDo(t, os.WriteFile(path, []byte(
+		`{"ts":"2025-11-13T21:00:00Z","msg":"one","trace_id":9007199254740993}`+"\n"+
+			`{"ts":"2025-11-13T21:00:01Z","msg":"two","trace_id":9007199254740992}`+"\n"+
+			`{"ts":"2025-11-13T21:00:02Z","msg":"three","trace_id":9007199254740993}`+"\n"), 0644))
+	st = newStore(t, decoder(t, nil, nil))
+	mustDo(t, st.Load(t.Context(), path, parcours.LoadOptions{}))
+
+	filter, label, err := parcours.Correlate(t.Context(), st, "1", []string{"trace_id"})
+	mustDo(t, err)
+	mustDo(t, st.SetView(t.Context(), filter, nil))
+	var ids []string
+	for _, ln := range page(t, st, 0, 99) {
+		ids = append(ids, ln[0].String())
+	}
+	if label != "trace_id=9007199254740993" || !slices.Equal(ids, []string{"1", "3"}) {
+		t.Errorf("expected lines 1 and 3 with %s, got %v with %s", "trace_id=9007199254740993", ids, label)
+	}
+}
+
+// testSurround views matches with lines around them, grouped into runs
+func testSurround(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+	surrounder, ok := st.(parcours.Surrounder)
+	if !ok {
+		t.Skip("store does not surround matches")
+	}
+
+	stopped := parcours.Filter{Op: parcours.Or, Children: []*parcours.Filter{
+		ptr(eq("message", "starting up")),
+		ptr(eq("message", "worker stopped")),
+	}}
+
+	tests := []struct {
+		name     string
+		filter   parcours.Filter
+		surround parcours.Surround
+		groups   [][]string
+		matches  []string
+	}{
+		{
+			name:     "by id",
+			filter:   stopped,
+			surround: parcours.Surround{Lines: 1},
+			groups:   [][]string{{"1", "2", "3"}, {"15", "16", "17", "18"}},
+			matches:  []string{"2", "16", "17"},
+		},
+		{
+			name:     "by timestamp",
+			filter:   eq("message", "listening"),
+			surround: parcours.Surround{Lines: 1, By: "timestamp"},
+			groups:   [][]string{{"5", "7", "6"}},
+			matches:  []string{"7"},
+		},
+		{
+			name:     "overlapping",
+			filter:   stopped,
+			surround: parcours.Surround{Lines: 2},
+			groups:   [][]string{{"1", "2", "3", "4"}, {"14", "15", "16", "17", "18", "19"}},
+			matches:  []string{"2", "16", "17"},
+		},
+		{
+			name:     "no matches",
+			filter:   eq("level", "fatal"),
+			surround: parcours.Surround{Lines: 3},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mustDo(t, st.SetView(t.Context(), tc.filter, nil))
+			mustDo(t, surrounder.SetSurround(t.Context(), tc.surround))
+
+			fields, count := view(t, st)
+			match, group := len(fields)-2, len(fields)-1
+			if fields[match].Name != parcours.MatchField || fields[group].Name != parcours.GroupField {
+				t.Fatalf("expected match and group fields last, got %v", fields)
+			}
+
+			var ids, matches []string
+			var groups [][]string
+			var last any
+			for i, ln := range page(t, st, 0, 99) {
+				id := ln[0].String()
+				ids = append(ids, id)
+				if ln[match].Raw == true {
+					matches = append(matches, id)
+				}
+				if i == 0 || ln[group].Raw != last {
+					groups = append(groups, nil)
+				}
+				groups[len(groups)-1] = append(groups[len(groups)-1], id)
+				last = ln[group].Raw
+			}
+
+			if count != len(ids) {
+				t.Errorf("expected count %d, got %d", len(ids), count)
+			}
+			if !slices.EqualFunc(groups, tc.groups, slices.Equal) {
+				t.Errorf("expected groups %v, got %v", tc.groups, groups)
+			}
+			if !slices.Equal(matches, tc.matches) {
+				t.Errorf("expected matches %v, got %v", tc.matches, matches)
+			}
+
+			for i, id := range ids {
+				offset, err := st.Locate(t.Context(), id)
+				mustDo(t, err)
+				if offset != i {
+					t.Errorf("expected line %s at offset %d, got %d", id, i, offset)
+				}
+			}
+
+			seeker, ok := st.(parcours.Seeker)
+			if !ok || len(ids) == 0 {
+				return
+			}
+			lines, err := seeker.GetPageAt(t.Context(), parcours.Cursor{ID: ids[0]}, 99)
+			mustDo(t, err)
+			if len(lines) != len(ids)-1 || (len(lines) > 0 && lines[0][0].String() != ids[1]) {
+				t.Errorf("expected %v after %s, got %d lines", ids[1:], ids[0], len(lines))
+			}
+		})
+	}
+
+	mustDo(t, surrounder.SetSurround(t.Context(), parcours.Surround{}))
+	fields, count := view(t, st)
+	if count != 0 || slices.ContainsFunc(fields, func(field parcours.Field) bool { return field.Name == parcours.MatchField }) {
+		t.Errorf("expected the view without context and no matches, got %d lines of %v", count, fields)
+	}
+}
+
+// testCompare counts values either side of the end of startup, keeping
+// those on one side only
+func testCompare(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+	counter, ok := st.(parcours.Counter)
+	if !ok {
+		t.Skip("store does not count values")
+	}
+
+	freqs, total, err := counter.Frequencies(t.Context(), parcours.Filter{}, "message", 3)
+	mustDo(t, err)
+	expected := []parcours.Frequency{{Value: "received request", Count: 2}, {Value: "refreshing tagger", Count: 2}, {Value: "sending response", Count: 2}}
+	if total != 19 || !slices.Equal(freqs, expected) {
+		t.Errorf("expected %v of 19, got %v of %d", expected, freqs, total)
+	}
+
+	freqs, total, err = counter.Frequencies(t.Context(), eq("level", "debug"), "status", 10)
+	mustDo(t, err)
+	expected = []parcours.Frequency{{Value: "200", Count: 2}}
+	if total != 4 || !slices.Equal(freqs, expected) {
+		t.Errorf("expected %v of 4, got %v of %d", expected, freqs, total)
+	}
+
+	a := parcours.Side{Label: "startup", Filter: parcours.Filter{Op: parcours.Lte, Field: "id", Value: 11}}
+	b := parcours.Side{Label: "shutdown", Filter: parcours.Filter{Op: parcours.Gt, Field: "id", Value: 11}}
+	comparison, err := parcours.Compare(t.Context(), counter, a, b, []string{"message", "level"})
+	mustDo(t, err)
+	if comparison.TotalA != 11 || comparison.TotalB != 8 {
+		t.Errorf("expected 11 and 8 lines compared, got %d and %d", comparison.TotalA, comparison.TotalB)
+	}
+
+	differs := map[string]parcours.Difference{}
+	for _, diff := range comparison.Differences {
+		differs[diff.Field+"="+diff.Value] = diff
+	}
+	for _, same := range []string{"message=refreshing tagger", "level=info"} {
+		if _, ok := differs[same]; ok {
+			t.Errorf("expected %s not to differ", same)
+		}
+	}
+	debug, stopped := differs["level=debug"], differs["message=worker stopped"]
+	if debug.A != 4 || debug.B != 0 || stopped.A != 0 || stopped.B != 2 {
+		t.Errorf("expected debug and worker stopped on one side only, got %+v and %+v", debug, stopped)
+	}
+
+	_, filter := comparison.Example(stopped)
+	mustDo(t, st.SetView(t.Context(), filter, nil))
+	var ids []string
+	for _, ln := range page(t, st, 0, 99) {
+		ids = append(ids, ln[0].String())
+	}
+	if !slices.Equal(ids, []string{"16", "17"}) {
+		t.Errorf("expected examples 16 and 17, got %v", ids)
+	}
+}
+
+// testPatterns mines templates from messages, counting them in view
+func testPatterns(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "patterns.log")
+	patterner, ok := st.(parcours.Patterner)
+	if !ok {
+		t.Skip("store does not mine patterns")
+	}
+
+	patterns, err := patterner.Patterns(t.Context())
+	mustDo(t, err)
+	expected := []parcours.Pattern{
+		{ID: 2, Template: "cache miss for key <*>", Count: 3},
+		{ID: 3, Template: "connection from <*> closed", Count: 2},
+		{ID: 1, Template: "worker started", Count: 1},
+		{ID: 4, Template: "connection from carol refused after retrying", Count: 1},
+		{ID: 5, Template: "job failed: disk full", Count: 1},
+		{ID: 6, Template: "worker stopped", Count: 1},
+	}
+	if !slices.Equal(patterns, expected) {
+		t.Errorf("expected patterns %v, got %v", expected, patterns)
+	}
+
+	mustDo(t, st.SetView(t.Context(), eq("level", "error"), nil))
+	patterns, err = patterner.Patterns(t.Context())
+	mustDo(t, err)
+	expected = []parcours.Pattern{
+		{ID: 2, Template: "cache miss for key <*>", Count: 1},
+		{ID: 5, Template: "job failed: disk full", Count: 1},
+	}
+	if !slices.Equal(patterns, expected) {
+		t.Errorf("expected patterns in view %v, got %v", expected, patterns)
+	}
+
+	mustDo(t, st.SetView(t.Context(), eq(parcours.PatternField, int64(3)), nil))
+	var ids []string
+	for _, ln := range page(t, st, 0, 99) {
+		ids = append(ids, ln[0].String())
+	}
+	if !slices.Equal(ids, []string{"3", "6"}) {
+		t.Errorf("expected lines 3 and 6 with pattern 3, got %v", ids)
+	}
+}
+
+// testRates counts heartbeats every 10s over 10m, expecting a burst of
+// errors in one bucket to be unusual
+func testRates(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "burst.log")
+	rater, ok := st.(parcours.Rater)
+	if !ok {
+		t.Skip("store does not count lines over time")
+	}
+
+	rates, width, err := rater.Rates(t.Context(), "level", 60)
+	mustDo(t, err)
+	if width != 10*time.Second || len(rates) != 62 {
+		t.Fatalf("expected 62 rates by 10s, got %d by %s", len(rates), width)
+	}
+
+	start := time.Date(2025, 11, 13, 20, 5, 0, 0, time.UTC)
+	burst := parcours.Rate{Start: start, Value: "error", Count: 8, First: "32"}
+	if !slices.ContainsFunc(rates, func(rate parcours.Rate) bool { return rate == burst }) {
+		t.Errorf("expected rate %+v, got %+v", burst, rates)
+	}
+
+	tl := parcours.NewTimeline("level", rates, width)
+	if len(tl.Totals) != 61 || tl.Totals[30] != 9 {
+		t.Errorf("expected 61 buckets with 9 lines in the 31st, got %v", tl.Totals)
+	}
+	if len(tl.Anomalies) != 1 || tl.Anomalies[0].Rate != burst {
+		t.Fatalf("expected the burst alone to be unusual, got %+v", tl.Anomalies)
+	}
+	if next, ok := tl.Next(start.Add(time.Second)); !ok || next.First != "32" {
+		t.Errorf("expected the next anomaly to wrap around to the burst, got %+v", next)
+	}
+
+	mustDo(t, st.SetView(t.Context(), eq("level", "info"), nil))
+	rates, _, err = rater.Rates(t.Context(), "level", 60)
+	mustDo(t, err)
+	if len(rates) != 61 || slices.ContainsFunc(rates, func(rate parcours.Rate) bool { return rate.Value != "info" }) {
+		t.Errorf("expected info rates in view alone, got %+v", rates)
+	}
+}
+
+// testStats summarizes request durations, some missing or not numbers,
+// overall and by path
+func testStats(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "requests.log")
+	summarizer, ok := st.(parcours.Summarizer)
+	if !ok {
+		t.Skip("store does not summarize fields")
+	}
+
+	expectStats := func(expected, got []parcours.Stats) {
+		t.Helper()
+		near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+		if !slices.EqualFunc(expected, got, func(a, b parcours.Stats) bool {
+			return a.Group == b.Group && a.Count == b.Count && near(a.Min, b.Min) && near(a.Max, b.Max) &&
+				near(a.Mean, b.Mean) && near(a.P50, b.P50) && near(a.P90, b.P90) && near(a.P99, b.P99)
+		}) {
+			t.Errorf("expected stats %+v, got %+v", expected, got)
+		}
+	}
+
+	stats, err := summarizer.Summarize(t.Context(), "duration_ms", "", 10)
+	mustDo(t, err)
+	expectStats([]parcours.Stats{
+		{Count: 8, Min: 10, Max: 300, Mean: 93.75, P50: 45, P90: 230, P99: 293},
+	}, stats)
+
+	stats, err = summarizer.Summarize(t.Context(), "duration_ms", "path", 10)
+	mustDo(t, err)
+	expectStats([]parcours.Stats{
+		{Group: "/a", Count: 5, Min: 10, Max: 50, Mean: 30, P50: 30, P90: 46, P99: 49.6},
+		{Group: "/b", Count: 3, Min: 100, Max: 300, Mean: 200, P50: 200, P90: 280, P99: 298},
+	}, stats)
+
+	stats, err = summarizer.Summarize(t.Context(), "duration_ms", "path", 1)
+	mustDo(t, err)
+	if len(stats) != 1 || stats[0].Group != "/a" {
+		t.Errorf("expected the largest group alone, got %+v", stats)
+	}
+
+	mustDo(t, st.SetView(t.Context(), eq("level", "error"), nil))
+	stats, err = summarizer.Summarize(t.Context(), "status", "path", 10)
+	mustDo(t, err)
+	expectStats([]parcours.Stats{
+		{Group: "/b", Count: 3, Min: 500, Max: 500, Mean: 500, P50: 500, P90: 500, P99: 500},
+	}, stats)
+}
+
+func testPromote(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+	mustDo(t, st.Promote(t.Context(), "worker_id"))
+	mustDo(t, st.Promote(t.Context(), "run_id"))
+
+	fields, _ := view(t, st)
+	expectFields(t, fields, "id", "timestamp", "level", "message", "worker_id", "run_id")
+
+	lines := page(t, st, 0, 3)
+	tests := []struct {
+		row, col int
+		expected string
+	}{
+		{row: 0, col: 4, expected: ""},
+		{row: 2, col: 4, expected: "X43f8xw"},
+		{row: 0, col: 5, expected: "QYN0wdE"},
+	}
+	for _, tc := range tests {
+		if got := lines[tc.row][tc.col].String(); got != tc.expected {
+			t.Errorf("expected %q at %d,%d, got %q", tc.expected, tc.row, tc.col, got)
+		}
+	}
+
+	// promoting again is harmless
+	mustDo(t, st.Promote(t.Context(), "worker_id"))
+	fields, _ = view(t, st)
+	if len(fields) != 6 {
+		t.Errorf("expected 6 fields after repeat promotion, got %d", len(fields))
+	}
+}
+
+func testJson(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+
+	data, err := st.GetJson(t.Context(), "3")
+	mustDo(t, err)
+
+	expected := map[string]string{
+		"msg":       "worker starting",
+		"worker_id": "X43f8xw",
+		"name":      "webhook service",
+		"ts":        "2025-11-13T20:08:27.881321Z",
+	}
+	for key, val := range expected {
+		if data[key] != val {
+			t.Errorf("expected %s of %q, got %v", key, val, data[key])
+		}
+	}
+
+	_, err = st.GetJson(t.Context(), "999")
+	if err == nil {
+		t.Errorf("expected error for unknown id")
+	}
+}
+
+func testLevels(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "levels.log")
+
+	expected := []string{"warn", "warn", "warn", "warn", "fatal", "info", "error", "trace"}
+	lines := page(t, st, 0, 99)
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(lines))
+	}
+
+	start := time.Date(2025, 11, 13, 20, 0, 0, 0, time.UTC)
+	for i, line := range lines {
+		if lvl := line[2].String(); lvl != expected[i] {
+			t.Errorf("line %d: expected level %s, got %s", i+1, expected[i], lvl)
+		}
+
+		ts, err := line[1].Time()
+		if err != nil {
+			t.Errorf("line %d: expected timestamp: %v", i+1, err)
+			continue
+		}
+		if want := start.Add(time.Duration(i) * time.Second); !ts.Equal(want) {
+			t.Errorf("line %d: expected timestamp %s, got %s", i+1, want, ts)
+		}
+	}
+
+	mustDo(t, st.SetView(t.Context(), parcours.Filter{Op: parcours.Gte, Field: "level", Value: "WARNING"}, nil))
+	_, count := view(t, st)
+	if count != 6 {
+		t.Errorf("expected 6 lines of warn and above, got %d", count)
+	}
+
+	mustDo(t, st.SetView(t.Context(), parcours.Filter{Op: parcours.Lt, Field: "level", Value: parcours.LevelWarn}, nil))
+	_, count = view(t, st)
+	if count != 2 {
+		t.Errorf("expected 2 lines below warn, got %d", count)
+	}
+}
+
+func testMultiline(t *testing.T, newStore NewStore) {
+
+	multi := &parcours.Multiline{
+		Pattern: `^(\s|exit status \d+$|goroutine \d+ \[|\[signal |[\w./*()-]+\(.*\)$)`,
+	}
+	st := newStore(t, decoder(t, nil, multi))
+	mustDo(t, st.Load(t.Context(), Fixture("panic.log"), parcours.LoadOptions{}))
+
+	_, count := view(t, st)
+	if count != 4 {
+		t.Fatalf("expected 4 records, got %d", count)
+	}
+
+	lines := page(t, st, 0, 4)
+	id := lines[2][0].String()
+	data, err := st.GetJson(t.Context(), id)
+	mustDo(t, err)
+
+	stack, _ := data["stack"].(string)
+	expected := "[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x533f73]\n" +
+		"goroutine 1 [running]:\n" +
+		"parcours/store/duck.(*Duck).Load(...)\n" +
+		"\t/root/module/store/duck/duck.go:44\n" +
+		"main.main()\n" +
+		"\t/root/module/cmd/tablo/main.go:11 +0x73\n" +
+		"exit status 2"
+	if stack != expected {
+		t.Errorf("unexpected stack:\n%s", stack)
+	}
+	if msg := lines[3][3].String(); msg != "three" {
+		t.Errorf("expected record after stack to be three, got %q", msg)
+	}
+}
+
+func testParser(t *testing.T, newStore NewStore) {
+
+	parser := &parcours.Parser{
+		Name:       "nginx",
+		Grok:       `%{IPORHOST:remote_addr} - %{NOTSPACE:remote_user} \[%{HTTPDATE:timestamp}\] "%{HTTPMETHOD:method} %{NOTSPACE:path} HTTP/%{HTTPVERSION:http_version}" %{INT:status} %{INT:bytes} %{QS:referer} %{QS:user_agent}`,
+		TimeFormat: "02/Jan/2006:15:04:05 -0700",
+	}
+	st := newStore(t, decoder(t, parser, nil))
+	mustDo(t, st.Load(t.Context(), Fixture("nginx.log"), parcours.LoadOptions{}))
+	mustDo(t, st.Promote(t.Context(), "status"))
+
+	mustDo(t, st.SetView(t.Context(), eq("status", "200"), nil))
+	_, count := view(t, st)
+	if count != 2 {
+		t.Errorf("expected 2 ok requests, got %d", count)
+	}
+
+	lines := page(t, st, 0, 1)
+	ts, err := lines[0][1].Time()
+	mustDo(t, err)
+	if want := time.Date(2000, 10, 10, 20, 55, 36, 0, time.UTC); !ts.Equal(want) {
+		t.Errorf("expected timestamp %s, got %s", want, ts)
+	}
+
+	data, err := st.GetJson(t.Context(), lines[0][0].String())
+	mustDo(t, err)
+	if data["path"] != "/apache_pb.gif" {
+		t.Errorf("expected captured path, got %v", data["path"])
+	}
+}
+
+func testSource(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+	sourcer, ok := st.(parcours.Sourcer)
+	if !ok {
+		t.Skip("store does not know sources of lines")
+	}
+	mustDo(t, st.Load(t.Context(), Fixture("levels.log"), parcours.LoadOptions{}))
+
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{id: "1", expected: Fixture("smar.log")},
+		{id: "19", expected: Fixture("smar.log")},
+		{id: "20", expected: Fixture("levels.log")},
+	}
+	for _, tc := range tests {
+		path, err := sourcer.Source(t.Context(), tc.id)
+		mustDo(t, err)
+		if path != tc.expected {
+			t.Errorf("expected line %s from %s, got %s", tc.id, tc.expected, path)
+		}
+	}
+
+	_, err := sourcer.Source(t.Context(), "0")
+	if err == nil {
+		t.Errorf("expected no source of line 0")
+	}
+}
+
+func testTail(t *testing.T, newStore NewStore) {
+
+	path := filepath.Join(t.TempDir(), "tail.log")
+	copyFile(t, Fixture("smar.log"), path)
+
+	st := newStore(t, decoder(t, nil, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mustDo(t, st.Follow(ctx, path, parcours.LoadOptions{}))
+	tail, err := st.Tail(ctx)
+	mustDo(t, err)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	mustDo(t, err)
+	_, err = file.WriteString(
+		`{"ts":"2025-11-13T21:00:00Z","level":"info","msg":"appended one"}` + "\n" +
+			`{"ts":"2025-11-13T21:00:01Z","level":"warn","msg":"appended two"}` + "\n")
+	mustDo(t, err)
+	mustDo(t, file.Close())
+
+	for _, expected := range []string{"appended one", "appended two"} {
+		select {
+		case line := <-tail:
+			if msg := line[3].String(); msg != expected {
+				t.Errorf("expected tailed %q, got %q", expected, msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", expected)
+		}
+	}
+
+	_, count := view(t, st)
+	if count != 21 {
+		t.Errorf("expected 21 lines after tail, got %d", count)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-tail:
+		if ok {
+			t.Errorf("expected tail to close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("timed out waiting for tail to close")
+	}
+}
+
+// testFollowFiles follows two files side by side, records spanning lines
+// being assembled from their own file alone
+func testFollowFiles(t *testing.T, newStore NewStore) {
+
+	st := newStore(t, decoder(t, nil, &parcours.Multiline{Pattern: `^\s`}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		path := filepath.Join(dir, name+".log")
+		mustDo(t, os.WriteFile(path, []byte(`{"ts":"2025-11-13T21:00:00Z","level":"info","msg":"`+name+` one"}`+"\n"), 0644))
+		mustDo(t, st.Follow(ctx, path, parcours.LoadOptions{}))
+	}
+	for _, name := range []string{"a", "b"} {
+		file, err := os.OpenFile(filepath.Join(dir, name+".log"), os.O_APPEND|os.O_WRONLY, 0)
+		mustDo(t, err)
+		_, err = file.WriteString(`{"ts":"2025-11-13T21:00:01Z","level":"error","msg":"` + name + ` two"}` + "\n" +
+			"\tat " + name + ".go:1\n\tat " + name + ".go:2\n")
+		mustDo(t, err)
+		mustDo(t, file.Close())
+	}
+
+	var count int
+	for range 50 {
+		_, count = view(t, st)
+		if count == 4 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 records from both files, got %d", count)
+	}
+
+	for _, line := range page(t, st, 0, 4) {
+		data, err := st.GetJson(t.Context(), line[0].String())
+		mustDo(t, err)
+
+		msg := line[3].String()
+		stack, _ := data["stack"].(string)
+		expected := ""
+		if name, ok := strings.CutSuffix(msg, " two"); ok {
+			expected = "\tat " + name + ".go:1\n\tat " + name + ".go:2"
+		}
+		if stack != expected {
+			t.Errorf("expected %q to have stack %q, got %q", msg, expected, stack)
+		}
+	}
+}
+
+// testFollowRotated rotates a followed file just after writing to it,
+// the last line unterminated, none of which may be lost
+func testFollowRotated(t *testing.T, newStore NewStore) {
+
+	st := newStore(t, decoder(t, nil, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	line := func(n int, msg string) string {
+		return fmt.Sprintf(`{"ts":"2025-11-13T21:00:0%dZ","level":"info","msg":"%s"}`, n, msg)
+	}
+
+	path := filepath.Join(t.TempDir(), "rotated.log")
+	mustDo(t, os.WriteFile(path, []byte(line(1, "one")+"\n"), 0644))
+	mustDo(t, st.Follow(ctx, path, parcours.LoadOptions{}))
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	mustDo(t, err)
+	_, err = file.WriteString(line(2, "two") + "\n" + line(3, "three"))
+	mustDo(t, err)
+	mustDo(t, file.Close())
+	mustDo(t, os.Rename(path, path+".1"))
+	mustDo(t, os.WriteFile(path, []byte(line(4, "four")+"\n"), 0644))
+
+	var count int
+	for range 50 {
+		_, count = view(t, st)
+		if count == 4 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if count != 4 {
+		t.Fatalf("expected 4 records across the rotation, got %d", count)
+	}
+
+	var msgs []string
+	for _, line := range page(t, st, 0, 4) {
+		msgs = append(msgs, line[3].String())
+	}
+	expected := []string{"one", "two", "three", "four"}
+	if !slices.Equal(msgs, expected) {
+		t.Errorf("expected %v across the rotation, got %v", expected, msgs)
+	}
+}
+
+// helpers
+
+func testCancel(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, _, err := st.GetView(ctx)
+	if err == nil {
+		t.Errorf("expected error counting with cancelled context")
+	}
+
+	_, err = st.GetPage(ctx, 0, 5)
+	if err == nil {
+		t.Errorf("expected error paging with cancelled context")
+	}
+
+	err = st.Load(ctx, Fixture("levels.log"), parcours.LoadOptions{})
+	if err == nil {
+		t.Errorf("expected error loading with cancelled context")
+	}
+
+	_, count := view(t, st)
+	if count != 19 {
+		t.Errorf("expected cancelled load to add nothing, got %d lines", count)
+	}
+}
+
+// testConcurrentView sets the view while pages are read, as the TUI does,
+// for the race detector to check
+func testConcurrentView(t *testing.T, newStore NewStore) {
+
+	st := loaded(t, newStore, "smar.log")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 20 {
+			filter := parcours.Filter{}
+			if i%2 == 0 {
+				filter = eq("level", "info")
+			}
+			err := st.SetView(t.Context(), filter, []parcours.Sort{{Field: "timestamp", Desc: i%2 == 0}})
+			if err != nil {
+				t.Errorf("failed to set view: %+v", err)
+			}
+		}
+	}()
+
+	for range 20 {
+		_, _, err := st.GetView(t.Context())
+		mustDo(t, err)
+		_, err = st.GetPage(t.Context(), 0, 5)
+		mustDo(t, err)
+	}
+	<-done
+}
+
+func decoder(t *testing.T, parser *parcours.Parser, multi *parcours.Multiline) *parcours.Decoder {
+
+	dec, err := parcours.NewDecoder(parser, multi)
+	mustDo(t, err)
+	return dec
+}
+
+func loaded(t *testing.T, newStore NewStore, fixture string) parcours.Store {
+
+	st := newStore(t, decoder(t, nil, nil))
+	mustDo(t, st.Load(t.Context(), Fixture(fixture), parcours.LoadOptions{}))
+	return st
+}
+
+func view(t *testing.T, st parcours.Store) (fields []parcours.Field, count int) {
+
+	t.Helper()
+	fields, count, err := st.GetView(t.Context())
+	mustDo(t, err)
+	return
+}
+
+func page(t *testing.T, st parcours.Store, offset, size int) (lines []parcours.Line) {
+
+	t.Helper()
+	lines, err := st.GetPage(t.Context(), offset, size)
+	mustDo(t, err)
+	return
+}
+
+func expectFields(t *testing.T, fields []parcours.Field, names ...string) {
+
+	t.Helper()
+	if len(fields) != len(names) {
+		t.Fatalf("expected fields %v, got %v", names, fields)
+	}
+	for i, name := range names {
+		if fields[i].Name != name {
+			t.Errorf("expected field %d to be %s, got %s", i, name, fields[i].Name)
+		}
+	}
+}
+
+func copyFile(t *testing.T, from, to string) {
+
+	data, err := os.ReadFile(from)
+	mustDo(t, err)
+	mustDo(t, os.WriteFile(to, data, 0644))
+}
+
+func eq(field string, value any) parcours.Filter {
+	return parcours.Filter{Op: parcours.Eq, Field: field, Value: value}
+}
+
+func ptr(filter parcours.Filter) *parcours.Filter {
+	return &filter
+}
+
+func mustDo(t *testing.T, err error) {
+
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package parcours
+
+import (
+	"context"
+	"slices"
+)
+
+// Fields appended to lines while surrounding matches.
+const (
+	// MatchField is true for matches of the filter, false for context around them
+	MatchField = "_match"
+	// GroupField is shared by runs of adjacent lines, changing across gaps
+	GroupField = "_group"
+)
+
+// Surround asks for lines of context around the matches of a view's
+// filter, as grep -C gives.
+type Surround struct {
+	// Lines before and after each match, off when zero
+	Lines int
+	// By orders the stream that neighbours are taken from, id when empty
+	By string
+}
+
+// Active reports whether matches are being surrounded.
+func (sur Surround) Active() bool {
+	return sur.Lines > 0
+}
+
+// Sorts ordering the stream.
+func (sur Surround) Sorts() []Sort {
+
+	if sur.By == "" || sur.By == "id" {
+		return nil
+	}
+	return []Sort{{Field: sur.By}}
+}
+
+// Surrounder is implemented by stores that show context around matches.
+// While surrounding, lines in view are matches and their neighbours, in
+// order of By rather than sorts, with MatchField and GroupField appended.
+type Surrounder interface {
+	// SetSurround for the view
+	SetSurround(ctx context.Context, surround Surround) (err error)
+}
+
+// surroundFields are appended to a view's fields while surrounding
+var surroundFields = []Field{{Name: MatchField, Type: "BOOLEAN"}, {Name: GroupField, Type: "BIGINT"}}
+
+// SurroundFields appends the fields lines carry while surrounding.
+func SurroundFields(fields []Field) []Field {
+	return slices.Concat(fields, surroundFields)
+}
@@ -3,13 +3,30 @@ package parcours
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"charm.land/lipgloss/v2"
 )
 
-func RenderTable(fields []Field, lines []Line, selectedRow, width int, layout *Layout) string {
+// timeLayout shows timestamps in columns without a format of their own
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// RenderTable renders lines per layout, with timestamps shown in zone
+// unless a column names its own, and the selected column's header
+// underlined. Bookmarked lines are marked in a column of their own, unless
+// bookmarks are nil.
+func RenderTable(fields []Field, lines []Line, selectedRow, selectedColumn, width int, layout *Layout, zone *time.Location, bookmarks *Bookmarks) string {
 	var b strings.Builder
 
+	// Timezones per column, resolved with the layout
+	locs := make([]*time.Location, len(layout.Columns))
+	for i, col := range layout.Columns {
+		locs[i] = zone
+		if col.Timezone != "" {
+			locs[i] = layout.Location(col.Timezone)
+		}
+	}
+
 	// Build field lookup maps
 	fieldMap := make(map[string]Field)
 	fieldIndex := make(map[string]int)
@@ -25,9 +42,16 @@ func RenderTable(fields []Field, lines []Line, selectedRow, width int, layout *L
 		if col.Hidden || col.Demote {
 			continue
 		}
-		cell := headerStyle.Width(col.Width).Render(col.Field)
+		style := headerStyle
+		if len(headerCols) == selectedColumn {
+			style = style.Underline(true)
+		}
+		cell := style.Width(col.Width).Render(col.Field)
 		headerCols = append(headerCols, cell)
 	}
+	if bookmarks != nil {
+		headerCols = append([]string{"  "}, headerCols...)
+	}
 	b.WriteString(lipgloss.JoinHorizontal(lipgloss.Left, headerCols...))
 	b.WriteString("\n")
 
@@ -36,22 +60,47 @@ func RenderTable(fields []Field, lines []Line, selectedRow, width int, layout *L
 	b.WriteString(sepStyle.Render(strings.Repeat("─", width)))
 	b.WriteString("\n")
 
+	// Lines around matches are dimmed, with groups of them set apart
+	match, surrounding := fieldIndex[MatchField]
+	group := fieldIndex[GroupField]
+	contextStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	markStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
 	// Data rows
 	for i, line := range lines {
+		if surrounding && i > 0 && line[group].Raw != lines[i-1][group].Raw {
+			b.WriteString(sepStyle.Render(strings.Repeat("╌", width)))
+			b.WriteString("\n")
+		}
+
 		var rowCols []string
-		for _, col := range layout.Columns {
+		if bookmarks != nil {
+			mark := "  "
+			if _, ok := bookmarks.Get(line.ID(fields)); ok {
+				mark = "● "
+			}
+			style := markStyle
+			if i == selectedRow {
+				style = style.Background(lipgloss.Color("63"))
+			}
+			rowCols = append(rowCols, style.Render(mark))
+		}
+		for j, col := range layout.Columns {
 			if col.Hidden || col.Demote {
 				continue
 			}
 
 			cellStyle := lipgloss.NewStyle()
+			if surrounding && line[match].Raw != true {
+				cellStyle = contextStyle
+			}
 			if i == selectedRow {
 				cellStyle = cellStyle.Background(lipgloss.Color("63"))
 			}
 
 			field := fieldMap[col.Field]
 			idx := fieldIndex[col.Field]
-			formatted := formatValue(line[idx], field.Type, col.Format)
+			formatted := formatValue(line[idx], field.Type, col.Format, locs[j])
 			cell := cellStyle.Width(col.Width).Render(formatted)
 			rowCols = append(rowCols, cell)
 		}
@@ -63,18 +112,59 @@ func RenderTable(fields []Field, lines []Line, selectedRow, width int, layout *L
 }
 
 // RenderFooter renders a footer with metadata about the table.
-func RenderFooter(totalLines, width int) string {
+func RenderFooter(totalLines, width int, zone *time.Location, surround Surround) string {
+	lines := fmt.Sprintf("Lines: %d", totalLines)
+	if surround.Active() {
+		lines += fmt.Sprintf(" | context ±%d", surround.Lines)
+	}
 	footer := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("240")).
-		Render(fmt.Sprintf("Lines: %d | ↑/↓ navigate | q quit", totalLines))
+		Render(fmt.Sprintf("%s | %s | ↑/↓ navigate | g/G top/end | r reverse | c correlate | d/D diff | p patterns | t timeline | ←/→ column | s/S stats | b/B bookmark | ' next bookmark | / filter | v/V views | +/- context | z zone | q quit", lines, zone))
 	return footer
 }
 
-func formatValue(val Value, fieldType, format string) string {
+// RenderProgress renders a progress bar, or counts when the fraction is unknown
+func RenderProgress(progress Progress, width int) string {
+
+	counts := fmt.Sprintf("%s | %s read | %d rows", progress.Phase, formatBytes(progress.Bytes), progress.Rows)
+	if progress.TotalBytes == 0 && progress.TotalRows == 0 {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(counts)
+	}
+
+	fraction := progress.Fraction()
+	barWidth := max(width-len(counts)-10, 10)
+	filled := int(fraction * float64(barWidth))
+
+	bar := lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(strings.Repeat("█", filled)) +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(strings.Repeat("░", barWidth-filled))
+
+	return fmt.Sprintf("%s %3.0f%% %s", bar, fraction*100, counts)
+}
+
+// formatBytes in binary units
+func formatBytes(bytes int64) string {
+
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func formatValue(val Value, fieldType, format string, loc *time.Location) string {
 	// TODO: Duck should normalize field types (TIMESTAMP -> timestamp)
-	if format != "" && fieldType == "TIMESTAMP" {
+	if fieldType == "TIMESTAMP" {
 		if t, err := val.Time(); err == nil {
-			return t.Format(format)
+			if format == "" {
+				format = timeLayout
+			}
+			return t.In(loc).Format(format)
 		}
 	}
 	return val.String()
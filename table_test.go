@@ -0,0 +1,38 @@
+package parcours
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatValue(t *testing.T) {
+
+	ts := time.Date(2025, 11, 13, 20, 30, 5, 123456789, time.UTC)
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatalf("failed to load location: %+v", err)
+	}
+
+	tests := []struct {
+		name      string
+		val       Value
+		fieldType string
+		format    string
+		loc       *time.Location
+		expected  string
+	}{
+		{name: "default layout", val: Value{Raw: ts}, fieldType: "TIMESTAMP", loc: time.UTC, expected: "2025-11-13 20:30:05.123"},
+		{name: "default layout in zone", val: Value{Raw: ts}, fieldType: "TIMESTAMP", loc: paris, expected: "2025-11-13 21:30:05.123"},
+		{name: "column format", val: Value{Raw: ts}, fieldType: "TIMESTAMP", format: "15:04:05", loc: time.UTC, expected: "20:30:05"},
+		{name: "null timestamp", val: Value{}, fieldType: "TIMESTAMP", loc: time.UTC, expected: ""},
+		{name: "text", val: Value{Raw: "hello"}, fieldType: "VARCHAR", format: "15:04", loc: time.UTC, expected: "hello"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatValue(tc.val, tc.fieldType, tc.format, tc.loc); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
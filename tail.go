@@ -0,0 +1,72 @@
+package parcours
+
+import (
+	"context"
+	"sync"
+)
+
+const tailBuffer = 1024
+
+// Broadcast fans lines out to tail subscribers, for stores implementing Tail.
+type Broadcast struct {
+	mu   sync.Mutex
+	subs []*subscriber
+}
+
+type subscriber struct {
+	ctx   context.Context
+	lines chan Line
+}
+
+// Subscribe to lines published until ctx is done, when lines is closed.
+func (bc *Broadcast) Subscribe(ctx context.Context) (lines <-chan Line) {
+
+	sub := &subscriber{
+		ctx:   ctx,
+		lines: make(chan Line, tailBuffer),
+	}
+
+	bc.mu.Lock()
+	bc.subs = append(bc.subs, sub)
+	bc.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		bc.mu.Lock()
+		defer bc.mu.Unlock()
+		for i := range bc.subs {
+			if bc.subs[i] == sub {
+				bc.subs = append(bc.subs[:i], bc.subs[i+1:]...)
+				break
+			}
+		}
+		close(sub.lines)
+	}()
+
+	return sub.lines
+}
+
+// Active reports whether there are any subscribers.
+func (bc *Broadcast) Active() bool {
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	return len(bc.subs) > 0
+}
+
+// Publish lines to subscribers, waiting on those that are behind.
+func (bc *Broadcast) Publish(lines []Line) {
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	for _, sub := range bc.subs {
+		for _, line := range lines {
+			select {
+			case sub.lines <- line:
+			case <-sub.ctx.Done():
+			}
+		}
+	}
+}
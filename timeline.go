@@ -0,0 +1,258 @@
+package parcours
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+)
+
+const (
+	// timelineBuckets is about how many buckets a timeline is divided into
+	timelineBuckets = 60
+	// anomalyScore is how far a bucket's count must be from its value's
+	// usual count, in standard deviations, to be unusual
+	anomalyScore = 4
+)
+
+// bucketWidths are the widths buckets are rounded up to
+var bucketWidths = []time.Duration{
+	time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+}
+
+// sparks draw counts from none to the most
+var sparks = []rune(" ▁▂▃▄▅▆▇█")
+
+// Rate of lines with a value in a bucket of time.
+type Rate struct {
+	Start time.Time `json:"start"`
+	Value string    `json:"value"`
+	Count int       `json:"count"`
+	// First line in the bucket with the value, by time
+	First string `json:"first"`
+}
+
+// Rater is implemented by stores that count lines over time.
+type Rater interface {
+	// Rates of lines in view by field's value, in buckets of about a
+	// buckets'th of the time they span, in order of time, with the width
+	Rates(ctx context.Context, field string, buckets int) (rates []Rate, width time.Duration, err error)
+}
+
+// Anomaly is a bucket where a value's count is unusual.
+type Anomaly struct {
+	Rate
+	// Usual count of the value in a bucket
+	Usual float64
+	// Score is how far the count is from usual, in standard deviations
+	Score float64
+}
+
+// Timeline of lines in view, with unusual buckets.
+type Timeline struct {
+	Field     string
+	Start     time.Time
+	Width     time.Duration
+	Totals    []int
+	Anomalies []Anomaly
+}
+
+// BucketWidth divides span into about buckets, rounded up to a round width.
+func BucketWidth(span time.Duration, buckets int) time.Duration {
+
+	width := span / time.Duration(max(buckets, 1))
+	for _, round := range bucketWidths {
+		if width <= round {
+			return round
+		}
+	}
+	return width.Round(24 * time.Hour)
+}
+
+// NewTimeline of rates in buckets of width, flagging buckets where a value
+// is counted unusually often or seldom, against the median count of each
+// value and its median absolute deviation, or the square root of the median
+// as counts would vary by chance, whichever is more.
+func NewTimeline(field string, rates []Rate, width time.Duration) (tl Timeline) {
+
+	tl.Field, tl.Width = field, width
+	if len(rates) == 0 || width <= 0 {
+		return
+	}
+
+	tl.Start = rates[0].Start
+	bucket := func(start time.Time) int { return int(start.Sub(tl.Start) / width) }
+	tl.Totals = make([]int, bucket(rates[len(rates)-1].Start)+1)
+
+	series := map[string][]Rate{}
+	var values []string
+	for _, rate := range rates {
+		tl.Totals[bucket(rate.Start)] += rate.Count
+		if _, ok := series[rate.Value]; !ok {
+			values = append(values, rate.Value)
+		}
+		series[rate.Value] = append(series[rate.Value], rate)
+	}
+
+	for _, value := range values {
+		counts := make([]float64, len(tl.Totals))
+		for _, rate := range series[value] {
+			counts[bucket(rate.Start)] = float64(rate.Count)
+		}
+
+		usual := median(counts)
+		deviations := make([]float64, len(counts))
+		for i, count := range counts {
+			deviations[i] = math.Abs(count - usual)
+		}
+		// scaled so as to estimate a standard deviation
+		spread := max(1.4826*median(deviations), math.Sqrt(max(usual, 1)))
+
+		for _, rate := range series[value] {
+			score := (float64(rate.Count) - usual) / spread
+			if math.Abs(score) >= anomalyScore {
+				tl.Anomalies = append(tl.Anomalies, Anomaly{Rate: rate, Usual: usual, Score: score})
+			}
+		}
+	}
+
+	slices.SortStableFunc(tl.Anomalies, func(a, b Anomaly) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(math.Abs(b.Score), math.Abs(a.Score)))
+	})
+	return
+}
+
+// Next anomaly starting after ts, wrapping around to the first.
+func (tl Timeline) Next(ts time.Time) (anomaly Anomaly, ok bool) {
+
+	if len(tl.Anomalies) == 0 {
+		return
+	}
+	for _, anomaly := range tl.Anomalies {
+		if anomaly.Start.After(ts) {
+			return anomaly, true
+		}
+	}
+	return tl.Anomalies[0], true
+}
+
+// RenderTimeline renders totals as sparks, with a marker under unusual
+// buckets and a note of the anomaly at the selected time.
+func RenderTimeline(tl Timeline, selected time.Time, width int, zone *time.Location) string {
+
+	if len(tl.Totals) == 0 {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("no timestamps in view")
+	}
+
+	most := slices.Max(tl.Totals)
+	totals := tl.Totals[:min(len(tl.Totals), width)]
+	var line, markers strings.Builder
+	for _, total := range totals {
+		spark := 0
+		if total > 0 {
+			spark = max(total*(len(sparks)-1)/most, 1)
+		}
+		line.WriteRune(sparks[spark])
+	}
+
+	marked := make([]bool, len(totals))
+	for _, anomaly := range tl.Anomalies {
+		if at := int(anomaly.Start.Sub(tl.Start) / tl.Width); at < len(marked) {
+			marked[at] = true
+		}
+	}
+	for _, mark := range marked {
+		if mark {
+			markers.WriteRune('▲')
+		} else {
+			markers.WriteRune(' ')
+		}
+	}
+
+	note := fmt.Sprintf("%s by %s, %d anomalies | n next anomaly | t close",
+		tl.Start.In(zone).Format(time.DateTime), tl.Width, len(tl.Anomalies))
+	for _, anomaly := range tl.Anomalies {
+		if !selected.Before(anomaly.Start) && selected.Before(anomaly.Start.Add(tl.Width)) {
+			note = fmt.Sprintf("%s=%s: %d lines, usually %.0f | %s", tl.Field, anomaly.Value, anomaly.Count, anomaly.Usual, note)
+			break
+		}
+	}
+
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(line.String()) + "\n" +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render(markers.String()) + "\n" +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Width(width).Render(note)
+}
+
+// TimelineField named by the layout, or level.
+func (layout *Layout) TimelineField() string {
+
+	if layout.Timeline == "" {
+		return "level"
+	}
+	return layout.Timeline
+}
+
+// unexported
+
+type timelineMsg struct {
+	timeline Timeline
+	err      error
+}
+
+// median of counts, which are reordered
+func median(counts []float64) float64 {
+
+	slices.Sort(counts)
+	mid := len(counts) / 2
+	if len(counts)%2 == 0 {
+		return (counts[mid-1] + counts[mid]) / 2
+	}
+	return counts[mid]
+}
+
+// timeline of the view, when the store counts lines over time
+func (m Model) timeline() tea.Cmd {
+	rater, ok := m.Store.(Rater)
+	if !ok {
+		return nil
+	}
+	field := m.Layout.TimelineField()
+	return func() tea.Msg {
+		rates, width, err := rater.Rates(m.loads.ctx, field, timelineBuckets)
+		return timelineMsg{timeline: NewTimeline(field, rates, width), err: err}
+	}
+}
+
+// selectedTime is the timestamp of the selected line, zero if it has none
+func (m Model) selectedTime() time.Time {
+	idx := slices.IndexFunc(m.Fields, func(field Field) bool { return field.Name == "timestamp" })
+	if idx < 0 || m.SelectedRow >= len(m.Lines) {
+		return time.Time{}
+	}
+	val := m.Lines[m.SelectedRow][idx]
+	ts, err := val.Time()
+	if err != nil {
+		ts = ParseTime(val.Raw, "")
+	}
+	return ts
+}
+
+// nextAnomaly jumps to the first line of the next unusual bucket
+func (m Model) nextAnomaly() (Model, tea.Cmd) {
+	if m.Timeline == nil {
+		return m, nil
+	}
+	anomaly, ok := m.Timeline.Next(m.selectedTime())
+	if !ok {
+		return m, nil
+	}
+	return m, m.jump(anomaly.First)
+}
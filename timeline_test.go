@@ -0,0 +1,114 @@
+package parcours
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBucketWidth(t *testing.T) {
+
+	tests := []struct {
+		span     time.Duration
+		buckets  int
+		expected time.Duration
+	}{
+		{span: 10 * time.Second, buckets: 60, expected: time.Second},
+		{span: time.Hour, buckets: 60, expected: time.Minute},
+		{span: 61 * time.Minute, buckets: 60, expected: 5 * time.Minute},
+		{span: 20 * time.Hour, buckets: 60, expected: 30 * time.Minute},
+		{span: 200 * 24 * time.Hour, buckets: 60, expected: 72 * time.Hour},
+		{span: time.Minute, buckets: 0, expected: time.Minute},
+	}
+
+	for _, tc := range tests {
+		if width := BucketWidth(tc.span, tc.buckets); width != tc.expected {
+			t.Errorf("expected %s in %d buckets to be %s wide, got %s", tc.span, tc.buckets, tc.expected, width)
+		}
+	}
+}
+
+func TestNewTimeline(t *testing.T) {
+
+	start := time.Date(2025, 11, 13, 20, 0, 0, 0, time.UTC)
+	at := func(bucket int) time.Time { return start.Add(time.Duration(bucket) * time.Minute) }
+
+	// counts by value in ten buckets, a minute each
+	counts := map[string][]int{
+		// steady, then a burst
+		"info": {10, 10, 10, 10, 10, 10, 60, 10, 10, 10},
+		// noisy by chance, then a burst well past the noise
+		"error": {1, 1, 1, 3, 1, 1, 1, 1, 6, 1},
+		// seen once, often
+		"warn": {0, 0, 5, 0, 0, 0, 0, 0, 0, 0},
+		// a dip
+		"debug": {100, 100, 100, 100, 20, 100, 100, 100, 100, 100},
+	}
+	var rates []Rate
+	totals := make([]int, 10)
+	for bucket := range 10 {
+		for _, value := range []string{"debug", "error", "info", "warn"} {
+			count := counts[value][bucket]
+			if count == 0 {
+				continue
+			}
+			rates = append(rates, Rate{Start: at(bucket), Value: value, Count: count})
+			totals[bucket] += count
+		}
+	}
+
+	tl := NewTimeline("level", rates, time.Minute)
+	if tl.Field != "level" || !tl.Start.Equal(start) || tl.Width != time.Minute {
+		t.Errorf("expected level timeline from %v by the minute, got %s from %v by %s", start, tl.Field, tl.Start, tl.Width)
+	}
+	if !reflect.DeepEqual(tl.Totals, totals) {
+		t.Errorf("expected totals %v, got %v", totals, tl.Totals)
+	}
+
+	expected := []struct {
+		bucket int
+		value  string
+		usual  float64
+		score  float64
+	}{
+		{bucket: 2, value: "warn", usual: 0, score: 5},
+		{bucket: 4, value: "debug", usual: 100, score: -8},
+		{bucket: 6, value: "info", usual: 10, score: 50 / math.Sqrt(10)},
+		{bucket: 8, value: "error", usual: 1, score: 5},
+	}
+	if len(tl.Anomalies) != len(expected) {
+		t.Fatalf("expected %d anomalies, got %+v", len(expected), tl.Anomalies)
+	}
+	for i, exp := range expected {
+		anomaly := tl.Anomalies[i]
+		if !anomaly.Start.Equal(at(exp.bucket)) || anomaly.Value != exp.value ||
+			anomaly.Usual != exp.usual || math.Abs(anomaly.Score-exp.score) > 1e-9 {
+			t.Errorf("expected %s anomaly in bucket %d, usually %v, scoring %.2f, got %+v", exp.value, exp.bucket, exp.usual, exp.score, anomaly)
+		}
+	}
+
+	// next after a time, wrapping around
+	for _, tc := range []struct {
+		after  time.Time
+		bucket int
+	}{
+		{after: time.Time{}, bucket: 2},
+		{after: at(2), bucket: 4},
+		{after: at(5).Add(30 * time.Second), bucket: 6},
+		{after: at(8), bucket: 2},
+	} {
+		anomaly, ok := tl.Next(tc.after)
+		if !ok || !anomaly.Start.Equal(at(tc.bucket)) {
+			t.Errorf("expected next anomaly after %v in bucket %d, got %+v %v", tc.after, tc.bucket, anomaly, ok)
+		}
+	}
+
+	empty := NewTimeline("level", nil, time.Minute)
+	if len(empty.Totals) != 0 || len(empty.Anomalies) != 0 {
+		t.Errorf("expected empty timeline of no rates, got %+v", empty)
+	}
+	if _, ok := empty.Next(start); ok {
+		t.Errorf("expected no next anomaly in an empty timeline")
+	}
+}
@@ -0,0 +1,146 @@
+package parcours
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are tried in order when detecting the format of a timestamp
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 Z07:00",
+	"2006-01-02 15:04:05.999999999 MST",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05,999999999",
+	"2006/01/02 15:04:05.999999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RubyDate,
+	time.UnixDate,
+	time.ANSIC,
+	time.StampNano,
+}
+
+// ParseTime converts a timestamp as logged, zero when it can't be.
+// Strings are parsed with format, a Go layout, when given, and numbers are
+// taken as epoch seconds, millis, micros or nanos, by magnitude unless
+// format says which.
+func ParseTime(val any, format string) (ts time.Time) {
+
+	switch val := val.(type) {
+	case json.Number:
+		ts = parseEpoch(string(val), format)
+	case float64:
+		ts = epochTime(val, format)
+	case int64:
+		ts = parseEpoch(strconv.FormatInt(val, 10), format)
+	case int:
+		ts = parseEpoch(strconv.Itoa(val), format)
+	case string:
+		if !strings.HasPrefix(format, "epoch") {
+			ts = parseTimeString(val, format)
+			if !ts.IsZero() || format != "" {
+				return
+			}
+		}
+		ts = parseEpoch(val, format)
+	}
+	return
+}
+
+func parseTimeString(str, format string) (ts time.Time) {
+
+	layouts := timeLayouts
+	if format != "" {
+		layouts = []string{format}
+	}
+
+	for _, layout := range layouts {
+		parsed, err := time.Parse(layout, strings.TrimSpace(str))
+		if err != nil {
+			continue
+		}
+
+		// syslog style stamps have no year, assume current
+		if parsed.Year() == 0 {
+			parsed = parsed.AddDate(time.Now().Year(), 0, 0)
+		}
+		ts = parsed
+		return
+	}
+	return
+}
+
+// parseEpoch parses an epoch number, exactly when it's plain decimal
+func parseEpoch(str, format string) (ts time.Time) {
+
+	str = strings.TrimSpace(str)
+	whole, frac, _ := strings.Cut(str, ".")
+
+	num, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || len(frac) > 0 && strings.Trim(frac, "0123456789") != "" {
+		if num, err := strconv.ParseFloat(str, 64); err == nil {
+			ts = epochTime(num, format)
+		}
+		return
+	}
+	if num <= 0 {
+		return
+	}
+
+	unit := epochUnit(float64(num), format)
+	nanos := num * unit
+	if frac != "" {
+		// nine digits of fraction, scaled from billionths of the unit
+		fracNum, _ := strconv.ParseInt((frac + "000000000")[:9], 10, 64)
+		nanos += fracNum * unit / 1e9
+	}
+
+	ts = time.Unix(0, nanos).UTC()
+	return
+}
+
+func epochTime(num float64, format string) (ts time.Time) {
+
+	if num <= 0 {
+		return
+	}
+
+	// whole units and the fraction scaled apart, so neither loses precision
+	unit := epochUnit(num, format)
+	whole, frac := math.Modf(num)
+	ts = time.Unix(0, int64(whole)*unit+int64(math.Round(frac*float64(unit)))).UTC()
+	return
+}
+
+// epochUnit returns nanos per unit of an epoch number, guessing by magnitude
+// when format doesn't say, eg 1.7e9 is seconds and 1.7e18 nanos.
+func epochUnit(num float64, format string) int64 {
+
+	switch format {
+	case "epoch_s":
+		return 1e9
+	case "epoch_ms":
+		return 1e6
+	case "epoch_us":
+		return 1e3
+	case "epoch_ns":
+		return 1
+	}
+
+	switch {
+	case num < 1e11:
+		return 1e9
+	case num < 1e14:
+		return 1e6
+	case num < 1e17:
+		return 1e3
+	}
+	return 1
+}
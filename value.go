@@ -2,6 +2,7 @@ package parcours
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/pkg/errors"
@@ -59,3 +60,16 @@ func (v Value) Time() (time.Time, error) {
 // Line represents a single log entry as an ordered list of values.
 // The order corresponds to the fields returned by Store.Fields().
 type Line []Value
+
+// IDField names the field identifying lines in their store.
+const IDField = "id"
+
+// ID of the line, its values being of fields, empty when it has none.
+func (line Line) ID(fields []Field) string {
+
+	idx := slices.IndexFunc(fields, func(field Field) bool { return field.Name == IDField })
+	if idx < 0 || idx >= len(line) {
+		return ""
+	}
+	return line[idx].String()
+}
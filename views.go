@@ -0,0 +1,235 @@
+package parcours
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// SavedView of lines matching a filter in an order, with the columns shown,
+// under a name.
+type SavedView struct {
+	Name   string `yaml:"name"`
+	Filter Filter `yaml:"filter"`
+	Sorts  []Sort `yaml:"sorts,omitempty"`
+	// Columns shown in order, the layout's when empty
+	Columns []string `yaml:"columns,omitempty"`
+}
+
+// Views saved in a file.
+type Views struct {
+	Path  string
+	Saved []SavedView
+}
+
+// LoadViews saved in the file at path, none when it's missing.
+func LoadViews(path string) (views *Views, err error) {
+
+	views = &Views{Path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = nil
+		return
+	}
+	if err != nil {
+		err = errors.Wrapf(err, "failed to read views")
+		return
+	}
+
+	err = yaml.Unmarshal(data, &views.Saved)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to parse views from %s", path)
+	}
+	return
+}
+
+// Save views to their file, replacing it whole.
+func (views *Views) Save() (err error) {
+
+	// indented as layouts are
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err = enc.Encode(views.Saved)
+	if err != nil {
+		err = errors.Wrapf(err, "failed to encode views")
+		return
+	}
+
+	err = os.WriteFile(views.Path, buf.Bytes(), 0o644)
+	err = errors.Wrapf(err, "failed to write views")
+	return
+}
+
+// Get the view saved under name.
+func (views *Views) Get(name string) (view SavedView, ok bool) {
+
+	idx := slices.IndexFunc(views.Saved, func(view SavedView) bool { return view.Name == name })
+	if idx < 0 {
+		return
+	}
+	return views.Saved[idx], true
+}
+
+// Set a view, replacing any saved under its name.
+func (views *Views) Set(view SavedView) {
+
+	idx := slices.IndexFunc(views.Saved, func(saved SavedView) bool { return saved.Name == view.Name })
+	if idx < 0 {
+		views.Saved = append(views.Saved, view)
+		return
+	}
+	views.Saved[idx] = view
+}
+
+// Select columns to show in order, others being hidden, or the layout's
+// own when none are. Fields without a column in the layout are passed over.
+func (layout *Layout) Select(fields []string) *Layout {
+
+	if len(fields) == 0 {
+		return layout
+	}
+
+	selected := *layout
+	selected.Columns = nil
+	for _, field := range fields {
+		idx := slices.IndexFunc(layout.Columns, func(col Column) bool { return col.Field == field })
+		if idx >= 0 && !layout.Columns[idx].Demote {
+			col := layout.Columns[idx]
+			col.Hidden = false
+			selected.Columns = append(selected.Columns, col)
+		}
+	}
+	for _, col := range layout.Columns {
+		if !slices.Contains(fields, col.Field) {
+			col.Hidden = true
+			selected.Columns = append(selected.Columns, col)
+		}
+	}
+	return &selected
+}
+
+// ViewMenu lists saved views to choose from, shown in place of the view.
+type ViewMenu struct {
+	Views *Views
+	// Results lists the views, a line for each
+	Results *Results
+}
+
+// NewViewMenu lists views.
+func NewViewMenu(views *Views) *ViewMenu {
+
+	fields := []Field{
+		{Name: "name", Type: "VARCHAR"},
+		{Name: "filter", Type: "VARCHAR"},
+		{Name: "sorts", Type: "VARCHAR"},
+		{Name: "columns", Type: "VARCHAR"},
+	}
+
+	lines := make([]Line, len(views.Saved))
+	for i, view := range views.Saved {
+		sorts := make([]string, len(view.Sorts))
+		for j, sort := range view.Sorts {
+			sorts[j] = sort.Field
+			if sort.Desc {
+				sorts[j] += " desc"
+			}
+		}
+		lines[i] = Line{{Raw: view.Name}, {Raw: view.Filter.String()}, {Raw: strings.Join(sorts, ", ")}, {Raw: strings.Join(view.Columns, ", ")}}
+	}
+
+	return &ViewMenu{
+		Views:   views,
+		Results: NewResults("", fields, lines),
+	}
+}
+
+// Selected view, if any.
+func (menu *ViewMenu) Selected() (view SavedView, ok bool) {
+
+	at := menu.Results.ScrollOffset + menu.Results.SelectedRow
+	if at >= len(menu.Views.Saved) {
+		return
+	}
+	return menu.Views.Saved[at], true
+}
+
+// View renders a page of saved views.
+func (menu *ViewMenu) View(width int, zone *time.Location) string {
+
+	header := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).
+		Render(fmt.Sprintf("%d views in %s | enter apply | V save current | esc close", len(menu.Views.Saved), menu.Views.Path))
+	res := menu.Results
+	return header + "\n" + RenderTable(res.Fields, res.Page(), res.SelectedRow, -1, width, res.Layout, zone, nil)
+}
+
+// unexported
+
+// layout with the columns selected, if any
+func (m Model) layout() *Layout {
+	return m.Layout.Select(m.Columns)
+}
+
+// applyView pivots to a saved view, with its columns
+func (m Model) applyView(view SavedView) (Model, tea.Cmd) {
+	m, cmd := m.pivot(view.Name, view.Filter, view.Sorts)
+	m.Columns = view.Columns
+	m.Crumbs[len(m.Crumbs)-1].Columns = view.Columns
+	return m, cmd
+}
+
+// openSaveView opens a prompt for the name to save the current view under
+func (m Model) openSaveView() (Model, tea.Cmd) {
+	if m.Views == nil {
+		return m, nil
+	}
+	name := ""
+	if len(m.Crumbs) > 0 {
+		name = m.Crumbs[len(m.Crumbs)-1].Label
+	}
+	m.Prompt, m.prompting = NewPrompt("save view as> ", name), viewPrompt
+	return m, nil
+}
+
+// saveView saves the current view under name, with the columns shown
+func (m Model) saveView(name string) Model {
+	var columns []string
+	for _, col := range m.layout().Visible() {
+		columns = append(columns, col.Field)
+	}
+	m.Views.Set(SavedView{Name: name, Filter: m.Filter, Sorts: m.Sorts, Columns: columns})
+	m.Err = m.Views.Save()
+	return m
+}
+
+// updateViewMenu navigates saved views until they're closed, applying the
+// selected one
+func (m Model) updateViewMenu(msg tea.KeyPressMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+	case "esc":
+		m.ViewMenu = nil
+	case "enter":
+		view, ok := m.ViewMenu.Selected()
+		if !ok {
+			return m, nil
+		}
+		m.ViewMenu = nil
+		return m.applyView(view)
+	case "V":
+		m.ViewMenu = nil
+		return m.openSaveView()
+	default:
+		m.ViewMenu.Results.Navigate(msg.String())
+	}
+	return m, nil
+}
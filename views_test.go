@@ -0,0 +1,54 @@
+package parcours
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestViews(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "views.yaml")
+	views, err := LoadViews(path)
+	if err != nil {
+		t.Fatalf("failed to load missing views: %+v", err)
+	}
+	if len(views.Saved) != 0 {
+		t.Fatalf("expected no views saved, got %+v", views.Saved)
+	}
+
+	errorsView := SavedView{
+		Name: "errors",
+		Filter: Filter{Op: And, Children: []*Filter{
+			{Op: Gte, Field: "level", Value: LevelError},
+			{Op: Gt, Field: "timestamp", Value: time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC)},
+			{Op: Ne, Field: "worker_id"},
+		}},
+		Sorts:   []Sort{{Field: "timestamp", Desc: true}, {Field: "id"}},
+		Columns: []string{"timestamp", "message"},
+	}
+	views.Set(SavedView{Name: "errors", Filter: Filter{Op: Eq, Field: "level", Value: "error"}})
+	views.Set(SavedView{Name: "slow", Filter: Filter{Op: Gt, Field: "duration", Value: 1.5}})
+	views.Set(errorsView)
+
+	err = views.Save()
+	if err != nil {
+		t.Fatalf("failed to save: %+v", err)
+	}
+	loaded, err := LoadViews(path)
+	if err != nil {
+		t.Fatalf("failed to load: %+v", err)
+	}
+	if !reflect.DeepEqual(loaded, views) {
+		t.Errorf("expected %+v after round trip, got %+v", views, loaded)
+	}
+
+	view, ok := loaded.Get("errors")
+	if !ok || !reflect.DeepEqual(view, errorsView) {
+		t.Errorf("expected view replaced by name, got %+v %v", view, ok)
+	}
+	if _, ok := loaded.Get("missing"); ok {
+		t.Errorf("expected no view under an unknown name")
+	}
+}
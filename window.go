@@ -0,0 +1,87 @@
+package parcours
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LoadOptions bound the records loaded from a file.
+type LoadOptions struct {
+	// Last keeps only the last so many records, all when zero
+	Last int
+	// Since skips records timestamped before, when not zero
+	Since time.Time
+	// Until skips records timestamped after, when not zero
+	Until time.Time
+}
+
+// Includes reports whether a record falls within since and until,
+// records without a timestamp falling outside any bound.
+func (opts LoadOptions) Includes(rec Record) bool {
+
+	if opts.Since.IsZero() && opts.Until.IsZero() {
+		return true
+	}
+
+	switch {
+	case rec.Timestamp.IsZero():
+		return false
+	case !opts.Since.IsZero() && rec.Timestamp.Before(opts.Since):
+		return false
+	case !opts.Until.IsZero() && rec.Timestamp.After(opts.Until):
+		return false
+	}
+	return true
+}
+
+// ParseBound parses a since or until bound, either a timestamp or relative
+// to now, such as "-2h", "-30m" or "-1d", and "now" itself.
+func ParseBound(val string, now time.Time) (bound time.Time, err error) {
+
+	val = strings.TrimSpace(val)
+	switch {
+	case val == "":
+		return
+	case val == "now":
+		bound = now
+		return
+	case strings.HasPrefix(val, "-") || strings.HasPrefix(val, "+"):
+		var dur time.Duration
+		dur, err = parseDuration(val)
+		if err != nil {
+			return
+		}
+		bound = now.Add(dur)
+		return
+	}
+
+	bound = ParseTime(val, "")
+	if bound.IsZero() {
+		err = errors.Errorf("failed to parse time bound %q", val)
+	}
+	return
+}
+
+// unexported
+
+// parseDuration parses as time.ParseDuration does, also allowing days
+func parseDuration(val string) (dur time.Duration, err error) {
+
+	if days, ok := strings.CutSuffix(val, "d"); ok {
+		var num float64
+		num, err = strconv.ParseFloat(days, 64)
+		if err != nil {
+			err = errors.Wrapf(err, "failed to parse days in %q", val)
+			return
+		}
+		dur = time.Duration(num * float64(24*time.Hour))
+		return
+	}
+
+	dur, err = time.ParseDuration(val)
+	err = errors.Wrapf(err, "failed to parse duration %q", val)
+	return
+}
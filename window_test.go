@@ -0,0 +1,79 @@
+package parcours
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBound(t *testing.T) {
+
+	now := time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		val      string
+		expected time.Time
+		fails    bool
+	}{
+		{val: ""},
+		{val: "now", expected: now},
+		{val: " now ", expected: now},
+		{val: "-2h", expected: now.Add(-2 * time.Hour)},
+		{val: "-1h30m", expected: now.Add(-90 * time.Minute)},
+		{val: "+15m", expected: now.Add(15 * time.Minute)},
+		{val: "-1d", expected: now.Add(-24 * time.Hour)},
+		{val: "-1.5d", expected: now.Add(-36 * time.Hour)},
+		{val: "2025-11-13T18:00:00Z", expected: time.Date(2025, 11, 13, 18, 0, 0, 0, time.UTC)},
+		{val: "2025-11-13 19:00:00+01:00", expected: time.Date(2025, 11, 13, 18, 0, 0, 0, time.UTC)},
+		{val: "1763065800", expected: now},
+		{val: "-2x", fails: true},
+		{val: "-d", fails: true},
+		{val: "yesterday", fails: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.val, func(t *testing.T) {
+			bound, err := ParseBound(tc.val, now)
+			if tc.fails {
+				if err == nil {
+					t.Errorf("expected error, got %v", bound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %+v", err)
+			}
+			if !bound.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, bound)
+			}
+		})
+	}
+}
+
+func TestIncludes(t *testing.T) {
+
+	at := time.Date(2025, 11, 13, 20, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		opts     LoadOptions
+		ts       time.Time
+		expected bool
+	}{
+		{name: "unbounded", ts: at, expected: true},
+		{name: "unbounded untimed", expected: true},
+		{name: "bounded untimed", opts: LoadOptions{Since: at}},
+		{name: "at since", opts: LoadOptions{Since: at}, ts: at, expected: true},
+		{name: "before since", opts: LoadOptions{Since: at}, ts: at.Add(-time.Second)},
+		{name: "at until", opts: LoadOptions{Until: at}, ts: at, expected: true},
+		{name: "after until", opts: LoadOptions{Until: at}, ts: at.Add(time.Second)},
+		{name: "within", opts: LoadOptions{Since: at.Add(-time.Hour), Until: at.Add(time.Hour)}, ts: at, expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.opts.Includes(Record{Timestamp: tc.ts}); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}